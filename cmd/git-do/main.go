@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/julianwyz/git-do/internal/cli"
+	"github.com/julianwyz/git-do/internal/git"
+	"github.com/julianwyz/git-do/internal/redact"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -58,6 +60,23 @@ func main() {
 		switch {
 		case errors.Is(err, cli.ErrNoCreds):
 			_, _ = os.Stdout.WriteString("No user credentials found. Have you ran `git do init` yet?\n")
+		case errors.Is(err, redact.ErrSecretFound):
+			exitCode = cli.ExitCodeSecretFound
+			_, _ = os.Stderr.WriteString("A possible secret was found in the changes. Nothing was sent.\n")
+		case errors.Is(err, cli.ErrAborted):
+			_, _ = os.Stderr.WriteString("Aborted.\n")
+		case errors.Is(err, cli.ErrHookNotManaged):
+			_, _ = os.Stderr.WriteString("The existing hook was not installed by `git do`. Leaving it in place.\n")
+		case errors.Is(err, cli.ErrAlreadyPushed):
+			_, _ = os.Stderr.WriteString("The commit has already been pushed to the upstream branch. Use `--force` to reword it anyway.\n")
+		case errors.Is(err, cli.ErrNotOnBranch):
+			_, _ = os.Stderr.WriteString("The commit is not on the current branch.\n")
+		case errors.Is(err, cli.ErrRewordArgs):
+			_, _ = os.Stderr.WriteString("`--reword` cannot be combined with `--amend` or arguments for `git commit`.\n")
+		case errors.Is(err, cli.ErrUnknownPair):
+			_, _ = os.Stderr.WriteString("Unknown pair alias. Pairs must be defined in a `[team]` roster.\n")
+		case errors.Is(err, git.ErrUnknownRef):
+			_, _ = os.Stderr.WriteString("Unknown commit reference.\n")
 		case errors.Is(err, cli.ErrNoProjectConfig):
 			_, _ = os.Stderr.WriteString("No project configuration file found in current directory. Have you ran `git do init` yet?\n")
 		default:
@@ -0,0 +1,331 @@
+// Package apidiff detects breaking changes to the exported
+// API of Go packages between two trees of source files.
+package apidiff
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+type (
+	// Tree of source files at one side of the change.
+	Tree interface {
+		// Files directly within dir, relative to the tree's root.
+		Files(dir string) []string
+		ReadFile(name string) ([]byte, error)
+	}
+
+	// Break of the API of a package.
+	Break struct {
+		// Package directory, relative to the tree's root.
+		Package string
+		Kind    Kind
+		// Object kind (e.g. "func" or "method") that was broken.
+		Object string
+		Name   string
+		// NewName of a renamed object.
+		NewName string
+		// Old and New descriptions of a changed object.
+		Old string
+		New string
+	}
+
+	Kind string
+
+	// api of a package, by the qualified name
+	// of each object (e.g. "Type.Method").
+	api map[string]apiObject
+
+	apiObject struct {
+		kind string
+		sig  string
+	}
+)
+
+const (
+	KindRemoved = Kind("removed")
+	KindRenamed = Kind("renamed")
+	KindChanged = Kind("changed")
+	// KindAdded to an interface, which breaks its implementations.
+	KindAdded = Kind("added")
+)
+
+const (
+	objectPackage         = "package"
+	objectInterfaceMethod = "interface method"
+)
+
+// Compare the API of each package containing one of the changed
+// paths, returning every break from the old tree to the new. A nil
+// tree has no files (e.g. before the first commit).
+//
+// Tests, main packages and internal packages are not
+// part of the public API and are not compared.
+func Compare(old, new Tree, paths []string) ([]Break, error) {
+	var breaks []Break
+	for _, dir := range packageDirs(paths) {
+		oldAPI, err := load(old, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if oldAPI == nil {
+			// a new package can't break anything
+			continue
+		}
+
+		newAPI, err := load(new, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if newAPI == nil {
+			breaks = append(breaks, Break{
+				Package: dir,
+				Kind:    KindRemoved,
+				Object:  objectPackage,
+				Name:    dir,
+			})
+
+			continue
+		}
+
+		breaks = append(breaks, compare(dir, oldAPI, newAPI)...)
+	}
+
+	return breaks, nil
+}
+
+func (recv Break) String() string {
+	var desc string
+	switch recv.Kind {
+	case KindRenamed:
+		desc = fmt.Sprintf("renamed %s %s to %s", recv.Object, recv.Name, recv.NewName)
+	case KindChanged:
+		desc = fmt.Sprintf("changed %s %s from `%s` to `%s`", recv.Object, recv.Name, recv.Old, recv.New)
+	case KindAdded:
+		desc = fmt.Sprintf("added %s %s `%s`", recv.Object, recv.Name, recv.New)
+	default:
+		desc = fmt.Sprintf("removed %s %s", recv.Object, recv.Name)
+	}
+
+	return fmt.Sprintf("%s: %s", recv.Package, desc)
+}
+
+// packageDirs of the non-test Go files within paths.
+func packageDirs(paths []string) []string {
+	var dirs []string
+	for _, p := range paths {
+		if !isSourceFile(p) {
+			continue
+		}
+
+		dir := path.Dir(p)
+		if slices.ContainsFunc(strings.Split(dir, "/"), func(elem string) bool {
+			return elem == "internal" || elem == "testdata" || elem == "vendor"
+		}) {
+			continue
+		}
+
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	slices.Sort(dirs)
+
+	return dirs
+}
+
+func isSourceFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+}
+
+// load the API of the package in dir. Nil is returned if
+// there is no package, or it is not importable.
+func load(tree Tree, dir string) (api, error) {
+	if tree == nil {
+		return nil, nil
+	}
+
+	var (
+		fset  = token.NewFileSet()
+		files []*ast.File
+	)
+	for _, name := range tree.Files(dir) {
+		if !isSourceFile(name) {
+			continue
+		}
+
+		src, err := tree.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if excluded(name, src) {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			// unparsable files, likely mid-change, are left out
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	if len(files) == 0 || files[0].Name.Name == "main" {
+		return nil, nil
+	}
+
+	conf := types.Config{
+		Importer:         newStubImporter(files),
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		// the package is checked in isolation, errors are
+		// expected and don't prevent describing its API
+		Error: func(error) {},
+	}
+	pkg, _ := conf.Check(dir, fset, files, nil)
+
+	return describe(pkg), nil
+}
+
+// excluded files are not built for the current platform, by
+// their name (e.g. "foo_windows.go") or build constraints, as
+// decided by the go tool.
+func excluded(name string, src []byte) bool {
+	ctxt := build.Default
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(src)), nil
+	}
+
+	match, err := ctxt.MatchFile(path.Dir(name), path.Base(name))
+	if err != nil {
+		// the parser decides what to do with malformed files
+		return false
+	}
+
+	return !match
+}
+
+// compare the APIs of a package.
+func compare(dir string, oldAPI, newAPI api) []Break {
+	var (
+		breaks  []Break
+		removed []string
+		added   []string
+	)
+
+	for _, name := range sortedNames(oldAPI) {
+		o := oldAPI[name]
+		n, found := newAPI[name]
+		switch {
+		case !found:
+			removed = append(removed, name)
+		case o.kind != n.kind || o.sig != n.sig:
+			breaks = append(breaks, Break{
+				Package: dir,
+				Kind:    KindChanged,
+				Object:  n.kind,
+				Name:    name,
+				Old:     o.sig,
+				New:     n.sig,
+			})
+		}
+	}
+
+	for _, name := range sortedNames(newAPI) {
+		if _, found := oldAPI[name]; !found {
+			added = append(added, name)
+		}
+	}
+
+	for _, name := range removed {
+		o := oldAPI[name]
+
+		// an identical object, with the same parent,
+		// that was added is likely the same one renamed
+		var renames []string
+		for _, a := range added {
+			n := newAPI[a]
+			if n.kind == o.kind && n.sig == o.sig && parent(a) == parent(name) {
+				renames = append(renames, a)
+			}
+		}
+
+		if len(renames) == 1 {
+			breaks = append(breaks, Break{
+				Package: dir,
+				Kind:    KindRenamed,
+				Object:  o.kind,
+				Name:    name,
+				NewName: renames[0],
+			})
+			added = slices.DeleteFunc(added, func(a string) bool {
+				return a == renames[0]
+			})
+
+			continue
+		}
+
+		breaks = append(breaks, Break{
+			Package: dir,
+			Kind:    KindRemoved,
+			Object:  o.kind,
+			Name:    name,
+		})
+	}
+
+	for _, name := range added {
+		n := newAPI[name]
+		if n.kind != objectInterfaceMethod {
+			continue
+		}
+
+		if _, found := oldAPI[parent(name)]; !found {
+			// a method of a new interface
+			continue
+		}
+
+		breaks = append(breaks, Break{
+			Package: dir,
+			Kind:    KindAdded,
+			Object:  n.kind,
+			Name:    name,
+			New:     n.sig,
+		})
+	}
+
+	return breaks
+}
+
+func parent(name string) string {
+	p, _, _ := strings.Cut(name, ".")
+	if p == name {
+		return ""
+	}
+
+	return p
+}
+
+func sortedNames(a api) []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
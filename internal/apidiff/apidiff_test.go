@@ -0,0 +1,157 @@
+package apidiff_test
+
+import (
+	"io/fs"
+	"path"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/julianwyz/git-do/internal/apidiff"
+)
+
+type (
+	// mapTree of file contents, by name.
+	mapTree map[string]string
+)
+
+func TestCompare(t *testing.T) {
+	old := mapTree{
+		"foo/foo.go": `package foo
+
+import "time"
+
+type Client struct {
+	Timeout time.Duration
+	Name    string
+	secret  string
+}
+
+func (c *Client) Do(name string) error { return nil }
+
+func (c Client) String() string { return "" }
+
+type Doer interface {
+	Do(name string) error
+}
+
+func New(name string) *Client { return nil }
+
+func Remove() {}
+
+func Before() {}
+
+const Version = "1"
+`,
+		"bar/bar.go": "package bar\n\nfunc Bar() {}\n",
+	}
+	updated := mapTree{
+		"foo/foo.go": `package foo
+
+import "time"
+
+type Client struct {
+	Timeout time.Duration
+	secret  int
+}
+
+func (c *Client) Do(ctx any, name string) error { return nil }
+
+func (c *Client) String() string { return "" }
+
+type Doer interface {
+	Do(name string) error
+	Close() error
+}
+
+func New(n string) *Client { return nil }
+
+func After() {}
+
+const Version = "2"
+`,
+	}
+
+	breaks, err := apidiff.Compare(old, updated, []string{
+		"foo/foo.go",
+		"bar/bar.go",
+		"internal/baz/baz.go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, b := range breaks {
+		actual = append(actual, b.String())
+	}
+
+	expected := []string{
+		"bar: removed package bar",
+		"foo: changed method Client.Do from `func (*Client) Do(string) error` to `func (*Client) Do(any, string) error`",
+		"foo: changed method Client.String from `func (Client) String() string` to `func (*Client) String() string`",
+		"foo: renamed func Before to After",
+		"foo: removed field Client.Name",
+		"foo: removed func Remove",
+		"foo: added interface method Doer.Close `func() error`",
+	}
+	slices.Sort(actual)
+	slices.Sort(expected)
+	if !slices.Equal(actual, expected) {
+		t.Fatalf("unexpected breaks:\n%q", actual)
+	}
+}
+
+func TestCompare__BuildConstraints(t *testing.T) {
+	other := "plan9"
+	if runtime.GOOS == other {
+		other = "windows"
+	}
+
+	old := mapTree{
+		"foo/foo.go": "package foo\n\nfunc Foo() {}\n\nfunc Bar() {}\n",
+	}
+	updated := mapTree{
+		// negated constraints are satisfied without the tag
+		"foo/foo.go":    "//go:build !sometag\n\npackage foo\n\nfunc Foo() {}\n",
+		"foo/ignore.go": "//go:build ignore\n\npackage foo\n\nfunc Bar() {}\n",
+		// files of other platforms are not built
+		"foo/bar_" + other + ".go": "package foo\n\nfunc Bar() {}\n",
+	}
+
+	breaks, err := apidiff.Compare(old, updated, []string{"foo/foo.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, b := range breaks {
+		actual = append(actual, b.String())
+	}
+
+	if !slices.Equal(actual, []string{"foo: removed func Bar"}) {
+		t.Fatalf("unexpected breaks:\n%q", actual)
+	}
+}
+
+func (recv mapTree) Files(dir string) []string {
+	var files []string
+	for name := range recv {
+		if path.Dir(name) == dir {
+			files = append(files, name)
+		}
+	}
+
+	slices.Sort(files)
+
+	return files
+}
+
+func (recv mapTree) ReadFile(name string) ([]byte, error) {
+	content, found := recv[name]
+	if !found {
+		return nil, fs.ErrNotExist
+	}
+
+	return []byte(content), nil
+}
@@ -0,0 +1,279 @@
+package apidiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+type (
+	// stubImporter of the packages imported by a set of files.
+	// Each package only declares the names the files reference,
+	// as types, so the package is described without checking
+	// (or even having) its dependencies.
+	stubImporter map[string]*types.Package
+)
+
+// describe the exported API of the package.
+func describe(pkg *types.Package) api {
+	var (
+		returner = api{}
+		scope    = pkg.Scope()
+		q        = qualifier(pkg)
+	)
+
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+
+		switch o := obj.(type) {
+		case *types.Func:
+			returner[name] = apiObject{
+				kind: "func",
+				sig:  signatureString(o.Type().(*types.Signature), q),
+			}
+		case *types.Var:
+			returner[name] = apiObject{
+				kind: "var",
+				sig:  types.TypeString(o.Type(), q),
+			}
+		case *types.Const:
+			returner[name] = apiObject{
+				kind: "const",
+				sig:  types.TypeString(o.Type(), q),
+			}
+		case *types.TypeName:
+			describeType(returner, o, q)
+		}
+	}
+
+	return returner
+}
+
+func describeType(dst api, tn *types.TypeName, q types.Qualifier) {
+	name := tn.Name()
+	if tn.IsAlias() {
+		dst[name] = apiObject{
+			kind: "type",
+			sig:  "= " + types.TypeString(tn.Type(), q),
+		}
+
+		return
+	}
+
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return
+	}
+
+	var tparams string
+	if tps := named.TypeParams(); tps.Len() > 0 {
+		var params []string
+		for tp := range tps.TypeParams() {
+			params = append(params, tp.Obj().Name()+" "+types.TypeString(tp.Constraint(), q))
+		}
+
+		tparams = "[" + strings.Join(params, ", ") + "] "
+	}
+
+	switch u := named.Underlying().(type) {
+	case *types.Struct:
+		dst[name] = apiObject{kind: "type", sig: tparams + "struct"}
+
+		for field := range u.Fields() {
+			if !field.Exported() {
+				continue
+			}
+
+			dst[name+"."+field.Name()] = apiObject{
+				kind: "field",
+				sig:  types.TypeString(field.Type(), q),
+			}
+		}
+	case *types.Interface:
+		dst[name] = apiObject{kind: "type", sig: tparams + "interface"}
+
+		for m := range u.Methods() {
+			if !m.Exported() {
+				continue
+			}
+
+			dst[name+"."+m.Name()] = apiObject{
+				kind: objectInterfaceMethod,
+				sig:  signatureString(m.Type().(*types.Signature), q),
+			}
+		}
+
+		// methods of interfaces are covered above
+		return
+	default:
+		dst[name] = apiObject{kind: "type", sig: tparams + types.TypeString(u, q)}
+	}
+
+	// methods of both T and *T, only the latter
+	// are lost by values of the type
+	pointerMethods := types.NewMethodSet(types.NewPointer(named))
+	valueMethods := types.NewMethodSet(named)
+	for sel := range pointerMethods.Methods() {
+		m := sel.Obj()
+		if !m.Exported() {
+			continue
+		}
+
+		receiver := "*" + name
+		if valueMethods.Lookup(m.Pkg(), m.Name()) != nil {
+			receiver = name
+		}
+
+		dst[name+"."+m.Name()] = apiObject{
+			kind: "method",
+			sig: fmt.Sprintf("func (%s) %s%s",
+				receiver,
+				m.Name(),
+				strings.TrimPrefix(signatureString(m.Type().(*types.Signature), q), "func"),
+			),
+		}
+	}
+}
+
+// signatureString of sig, without parameter names,
+// which can be changed without breaking callers.
+func signatureString(sig *types.Signature, q types.Qualifier) string {
+	var sb strings.Builder
+	sb.WriteString("func")
+
+	if tps := sig.TypeParams(); tps.Len() > 0 {
+		var params []string
+		for tp := range tps.TypeParams() {
+			params = append(params, tp.Obj().Name()+" "+types.TypeString(tp.Constraint(), q))
+		}
+
+		sb.WriteString("[" + strings.Join(params, ", ") + "]")
+	}
+
+	var params []string
+	for i := range sig.Params().Len() {
+		t := sig.Params().At(i).Type()
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			params = append(params, "..."+types.TypeString(t.(*types.Slice).Elem(), q))
+
+			continue
+		}
+
+		params = append(params, types.TypeString(t, q))
+	}
+
+	sb.WriteString("(" + strings.Join(params, ", ") + ")")
+
+	var results []string
+	for i := range sig.Results().Len() {
+		results = append(results, types.TypeString(sig.Results().At(i).Type(), q))
+	}
+
+	switch len(results) {
+	case 0:
+	case 1:
+		sb.WriteString(" " + results[0])
+	default:
+		sb.WriteString(" (" + strings.Join(results, ", ") + ")")
+	}
+
+	return sb.String()
+}
+
+// qualifier of types from packages other than pkg.
+func qualifier(pkg *types.Package) types.Qualifier {
+	return func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+
+		return p.Name()
+	}
+}
+
+func newStubImporter(files []*ast.File) stubImporter {
+	returner := stubImporter{}
+	for _, f := range files {
+		// the local name of each import, in this file
+		local := map[string]*types.Package{}
+		for _, spec := range f.Imports {
+			p, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+
+			pkg, found := returner[p]
+			if !found {
+				pkg = types.NewPackage(p, packageName(p))
+				returner[p] = pkg
+			}
+
+			name := pkg.Name()
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+
+			local[name] = pkg
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			ident, ok := sel.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+
+			pkg, found := local[ident.Name]
+			if !found || pkg.Scope().Lookup(sel.Sel.Name) != nil {
+				return true
+			}
+
+			tn := types.NewTypeName(ident.Pos(), pkg, sel.Sel.Name, nil)
+			types.NewNamed(tn, types.NewInterfaceType(nil, nil), nil)
+			pkg.Scope().Insert(tn)
+
+			return true
+		})
+	}
+
+	for _, pkg := range returner {
+		pkg.MarkComplete()
+	}
+
+	return returner
+}
+
+func (recv stubImporter) Import(p string) (*types.Package, error) {
+	if pkg, found := recv[p]; found {
+		return pkg, nil
+	}
+
+	return types.NewPackage(p, packageName(p)), nil
+}
+
+// packageName guessed from the import path, ignoring
+// any major version suffix (e.g. "example.com/foo/v2").
+func packageName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' {
+		if _, err := strconv.Atoi(name[1:]); err == nil {
+			name = elems[len(elems)-2]
+		}
+	}
+
+	// e.g. "gopkg.in/yaml.v3" or "github.com/foo/go-bar"
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+
+	return strings.ReplaceAll(name, "-", "_")
+}
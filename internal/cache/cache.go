@@ -0,0 +1,238 @@
+// Package cache is a content-addressed, on-disk cache of LLM responses.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	// Cache of responses, stored as one file per key.
+	Cache struct {
+		dir    string
+		config *cacheConfig
+	}
+
+	entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+)
+
+const (
+	// DefaultMaxAge of an entry before it is evicted.
+	DefaultMaxAge = 7 * 24 * time.Hour
+	// DefaultMaxSize of all entries, in bytes.
+	DefaultMaxSize = 50 << 20
+
+	// tmpPrefix of entries that are being written.
+	tmpPrefix = ".tmp-"
+)
+
+var (
+	ErrInvalidKey = errors.New("cache: invalid key")
+)
+
+// New cache of the entries stored in dir.
+func New(dir string, opts ...Opt) (*Cache, error) {
+	config := &cacheConfig{
+		maxAge:  DefaultMaxAge,
+		maxSize: DefaultMaxSize,
+		now:     time.Now,
+	}
+	for _, o := range opts {
+		if err := o(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Cache{
+		dir:    dir,
+		config: config,
+	}, nil
+}
+
+// Key addressing the content of the provided parts.
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		// length prefixed, so parts can't bleed into each other
+		_, _ = h.Write([]byte{
+			byte(len(p) >> 24), byte(len(p) >> 16),
+			byte(len(p) >> 8), byte(len(p)),
+		})
+		_, _ = h.Write(p)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get the entry stored at key. Expired entries are never returned.
+func (recv *Cache) Get(key string) ([]byte, bool) {
+	p, err := recv.path(key)
+	if err != nil {
+		return nil, false
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil || recv.expired(fi.ModTime()) {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+
+	log.Debug().
+		Str("key", key).
+		Msg("cache hit")
+
+	return content, true
+}
+
+// Put the content at key, evicting any entries that
+// exceed the cache's age or size limits.
+func (recv *Cache) Put(key string, content []byte) error {
+	p, err := recv.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	// written in full before it is visible to readers
+	tmp, err := os.CreateTemp(filepath.Dir(p), tmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return err
+	}
+
+	return recv.Evict()
+}
+
+// Evict entries older than the max age, then the oldest
+// entries until the cache is within its max size.
+func (recv *Cache) Evict() error {
+	entries, err := recv.entries()
+	if err != nil {
+		return err
+	}
+
+	// newest first, so the oldest are evicted by size
+	slices.SortFunc(entries, func(a, b entry) int {
+		return b.modTime.Compare(a.modTime)
+	})
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+		if !recv.expired(e.modTime) && !recv.oversized(total) {
+			continue
+		}
+
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		log.Debug().
+			Str("path", e.path).
+			Msg("cache eviction")
+	}
+
+	return nil
+}
+
+// Clear every entry from the cache, returning how many were removed.
+func (recv *Cache) Clear() (int, error) {
+	entries, err := recv.entries()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.RemoveAll(recv.dir); err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+func (recv *Cache) expired(modTime time.Time) bool {
+	return recv.config.maxAge > 0 &&
+		recv.config.now().Sub(modTime) > recv.config.maxAge
+}
+
+func (recv *Cache) oversized(total int64) bool {
+	return recv.config.maxSize > 0 && total > recv.config.maxSize
+}
+
+// path of the entry stored at key, sharded by the key's prefix.
+func (recv *Cache) path(key string) (string, error) {
+	if _, err := hex.DecodeString(key); err != nil || len(key) < 3 {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(recv.dir, key[:2], key), nil
+}
+
+func (recv *Cache) entries() ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(recv.dir, func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return fs.SkipAll
+		case err != nil:
+			return err
+		case d.IsDir():
+			return nil
+		case strings.HasPrefix(d.Name(), tmpPrefix):
+			// still being written by Put
+			return nil
+		}
+
+		fi, err := d.Info()
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// evicted concurrently
+			return nil
+		case err != nil:
+			return err
+		}
+
+		entries = append(entries, entry{
+			path:    p,
+			size:    fi.Size(),
+			modTime: fi.ModTime(),
+		})
+
+		return nil
+	})
+
+	return entries, err
+}
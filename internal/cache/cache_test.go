@@ -0,0 +1,147 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julianwyz/git-do/internal/cache"
+)
+
+func TestKey(t *testing.T) {
+	if cache.Key([]byte("a"), []byte("b")) == cache.Key([]byte("ab")) {
+		t.Fatal("expected parts to be distinct")
+	}
+
+	if cache.Key([]byte("a")) != cache.Key([]byte("a")) {
+		t.Fatal("expected key to be stable")
+	}
+}
+
+func TestCache(t *testing.T) {
+	c, err := cache.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := cache.Key([]byte("hello"))
+	if _, found := c.Get(key); found {
+		t.Fatal("expected empty cache")
+	}
+
+	if err := c.Put(key, []byte("world")); err != nil {
+		t.Fatal(err)
+	}
+
+	content, found := c.Get(key)
+	if !found || string(content) != "world" {
+		t.Fatal("expected cached content")
+	}
+
+	if err := c.Put("not a key", nil); err == nil {
+		t.Fatal("expected invalid key error")
+	}
+
+	n, err := c.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 1 {
+		t.Fatalf("expected 1 cleared entry, got %d", n)
+	}
+
+	if _, found := c.Get(key); found {
+		t.Fatal("expected cleared cache")
+	}
+}
+
+func TestCache__Eviction(t *testing.T) {
+	t.Run("age", func(t *testing.T) {
+		now := time.Now()
+		c, err := cache.New(
+			t.TempDir(),
+			cache.WithMaxAge(time.Hour),
+			cache.WithClock(func() time.Time { return now }),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key := cache.Key([]byte("hello"))
+		if err := c.Put(key, []byte("world")); err != nil {
+			t.Fatal(err)
+		}
+
+		now = now.Add(2 * time.Hour)
+		if _, found := c.Get(key); found {
+			t.Fatal("expected expired entry")
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		c, err := cache.New(
+			t.TempDir(),
+			cache.WithMaxSize(8),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first, second := cache.Key([]byte("first")), cache.Key([]byte("second"))
+		if err := c.Put(first, []byte("12345")); err != nil {
+			t.Fatal(err)
+		}
+
+		// ensure the entries have distinct ages
+		time.Sleep(10 * time.Millisecond)
+
+		if err := c.Put(second, []byte("12345")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, found := c.Get(first); found {
+			t.Fatal("expected the oldest entry to be evicted")
+		}
+
+		if _, found := c.Get(second); !found {
+			t.Fatal("expected the newest entry to be kept")
+		}
+	})
+
+	t.Run("in-flight writes", func(t *testing.T) {
+		dir := t.TempDir()
+		c, err := cache.New(
+			dir,
+			cache.WithMaxSize(8),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// an entry being written by another process
+		tmp := filepath.Join(dir, ".tmp-inflight")
+		if err := os.WriteFile(tmp, []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		key := cache.Key([]byte("hello"))
+		if err := c.Put(key, []byte("world")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Stat(tmp); err != nil {
+			t.Fatal("expected in-flight writes to not be evicted")
+		}
+
+		n, err := c.Clear()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 1 {
+			t.Fatalf("expected 1 cleared entry, got %d", n)
+		}
+	})
+}
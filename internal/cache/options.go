@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"time"
+)
+
+type (
+	cacheConfig struct {
+		maxAge  time.Duration
+		maxSize int64
+		now     func() time.Time
+	}
+
+	Opt func(*cacheConfig) error
+)
+
+// WithMaxAge of entries before they are evicted.
+// A zero age never evicts entries by age.
+func WithMaxAge(age time.Duration) Opt {
+	return func(cc *cacheConfig) error {
+		cc.maxAge = age
+
+		return nil
+	}
+}
+
+// WithMaxSize, in bytes, of all entries combined.
+// A zero size never evicts entries by size.
+func WithMaxSize(size int64) Opt {
+	return func(cc *cacheConfig) error {
+		cc.maxSize = size
+
+		return nil
+	}
+}
+
+// WithClock used to determine the age of entries.
+func WithClock(now func() time.Time) Opt {
+	return func(cc *cacheConfig) error {
+		cc.now = now
+
+		return nil
+	}
+}
@@ -0,0 +1,316 @@
+// Package changelog groups commits into sections of a changelog
+// following the Keep a Changelog format, see https://keepachangelog.com,
+// and suggests the semantic version of their release.
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/julianwyz/git-do/internal/git"
+)
+
+type (
+	// Category of a change, one of the Keep a Changelog sections.
+	Category string
+
+	// Entry of the changelog, describing a single commit.
+	Entry struct {
+		Hash     string
+		Category Category
+		Scope    string
+		Summary  string
+		// Breaking marks a change that breaks the public API.
+		Breaking bool
+		// BreakingNotes describing the breaking change, from
+		// the commit's BREAKING CHANGE footers.
+		BreakingNotes []string
+		// Closes references the issues resolved by the commit.
+		Closes []string
+	}
+
+	// Release section of the changelog.
+	Release struct {
+		// Version of the release. An empty version
+		// is the Unreleased section.
+		Version string
+		Date    time.Time
+		Entries []Entry
+	}
+)
+
+const (
+	CategoryAdded      = Category("Added")
+	CategoryChanged    = Category("Changed")
+	CategoryDeprecated = Category("Deprecated")
+	CategoryRemoved    = Category("Removed")
+	CategoryFixed      = Category("Fixed")
+	CategorySecurity   = Category("Security")
+	// CategoryOmitted changes are not listed (e.g. chores).
+	CategoryOmitted = Category("Omitted")
+
+	unreleased = "Unreleased"
+
+	// Header of a new changelog.
+	Header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+)
+
+var (
+	// Categories of listed changes, in the order of their sections.
+	Categories = []Category{
+		CategoryAdded,
+		CategoryChanged,
+		CategoryDeprecated,
+		CategoryRemoved,
+		CategoryFixed,
+		CategorySecurity,
+	}
+
+	// conventionalCategories of each conventional commit type.
+	conventionalCategories = map[string]Category{
+		"feat":     CategoryAdded,
+		"fix":      CategoryFixed,
+		"perf":     CategoryChanged,
+		"refactor": CategoryChanged,
+		"revert":   CategoryRemoved,
+		"docs":     CategoryOmitted,
+		"test":     CategoryOmitted,
+		"chore":    CategoryOmitted,
+		"build":    CategoryOmitted,
+		"ci":       CategoryOmitted,
+		"style":    CategoryOmitted,
+	}
+
+	conventionalHeaderPattern = regexp.MustCompile(
+		`^([a-zA-Z]+)(\(([^()]*)\))?(!)?: (.+)$`,
+	)
+	breakingFooterPattern = regexp.MustCompile(`^BREAKING[ -]CHANGE: (.+)$`)
+	closesTrailerPattern  = regexp.MustCompile(`^(?i:closes): (.+)$`)
+)
+
+// Parse the commit into an entry, categorized by its conventional
+// commit type. Commits that are not conventional commits have no
+// category, and are reported as not categorized.
+func Parse(commit git.LogEntry) (Entry, bool) {
+	returner := Entry{
+		Hash:    commit.Hash,
+		Summary: commit.Subject,
+	}
+
+	for line := range strings.SplitSeq(commit.Body, "\n") {
+		line = strings.TrimSpace(line)
+		if m := breakingFooterPattern.FindStringSubmatch(line); m != nil {
+			returner.Breaking = true
+			returner.BreakingNotes = append(returner.BreakingNotes, m[1])
+		}
+		if m := closesTrailerPattern.FindStringSubmatch(line); m != nil {
+			returner.Closes = append(returner.Closes, m[1])
+		}
+	}
+
+	m := conventionalHeaderPattern.FindStringSubmatch(commit.Subject)
+	if m == nil {
+		return returner, false
+	}
+
+	category, found := conventionalCategories[strings.ToLower(m[1])]
+	if !found {
+		return returner, false
+	}
+
+	returner.Category = category
+	returner.Scope = m[3]
+	returner.Summary = m[5]
+	returner.Breaking = returner.Breaking || len(m[4]) > 0
+
+	if returner.Breaking && returner.Category == CategoryOmitted {
+		// breaking changes are always worth listing
+		returner.Category = CategoryChanged
+	}
+
+	return returner, true
+}
+
+// String of the release, as a section of the changelog.
+func (recv Release) String() string {
+	var sb strings.Builder
+
+	if len(recv.Version) == 0 {
+		fmt.Fprintf(&sb, "## [%s]\n", unreleased)
+	} else {
+		fmt.Fprintf(&sb, "## [%s] - %s\n",
+			strings.TrimPrefix(recv.Version, "v"),
+			recv.Date.Format(time.DateOnly),
+		)
+	}
+
+	for _, category := range Categories {
+		var entries []Entry
+		for _, e := range recv.Entries {
+			if e.Category == category {
+				entries = append(entries, e)
+			}
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		// breaking changes are listed first
+		slices.SortStableFunc(entries, func(a, b Entry) int {
+			switch {
+			case a.Breaking == b.Breaking:
+				return 0
+			case a.Breaking:
+				return -1
+			default:
+				return 1
+			}
+		})
+
+		fmt.Fprintf(&sb, "\n### %s\n\n", category)
+		for _, e := range entries {
+			sb.WriteString(e.String())
+		}
+	}
+
+	return sb.String()
+}
+
+// String of the entry, as a list item of the changelog.
+func (recv Entry) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("- ")
+	if recv.Breaking {
+		sb.WriteString("**BREAKING:** ")
+	}
+	if len(recv.Scope) > 0 {
+		fmt.Fprintf(&sb, "**%s:** ", recv.Scope)
+	}
+
+	sb.WriteString(capitalize(recv.Summary))
+
+	if len(recv.Hash) > 0 {
+		fmt.Fprintf(&sb, " (%s)", shortHash(recv.Hash))
+	}
+
+	if len(recv.Closes) > 0 {
+		fmt.Fprintf(&sb, ", closes %s", strings.Join(recv.Closes, ", "))
+	}
+
+	sb.WriteString("\n")
+
+	for _, note := range recv.BreakingNotes {
+		fmt.Fprintf(&sb, "  - %s\n", note)
+	}
+
+	return sb.String()
+}
+
+// Insert the release into the changelog, above any previous
+// releases. An existing Unreleased section is replaced by an
+// Unreleased release, and otherwise kept above the release.
+//
+// An empty changelog is started with the Header.
+func Insert(changelog string, release Release) string {
+	if len(strings.TrimSpace(changelog)) == 0 {
+		changelog = Header
+	}
+
+	lines := strings.SplitAfter(changelog, "\n")
+
+	var (
+		at      = len(lines)
+		replace = -1
+	)
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+
+		if isUnreleased(line) {
+			if len(release.Version) == 0 {
+				at = i
+				replace = sectionEnd(lines, i)
+
+				break
+			}
+
+			continue
+		}
+
+		at = i
+
+		break
+	}
+
+	var sb strings.Builder
+	for _, line := range lines[:at] {
+		sb.WriteString(line)
+	}
+	if at > 0 && !strings.HasSuffix(sb.String(), "\n\n") {
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(release.String())
+
+	rest := lines[at:]
+	if replace >= 0 {
+		rest = lines[replace:]
+	}
+	if len(rest) > 0 {
+		sb.WriteString("\n")
+		for _, line := range rest {
+			sb.WriteString(line)
+		}
+	}
+
+	return sb.String()
+}
+
+func isUnreleased(heading string) bool {
+	return strings.Contains(
+		strings.ToLower(heading),
+		strings.ToLower(unreleased),
+	)
+}
+
+// sectionEnd is the line following the section starting at start.
+func sectionEnd(lines []string, start int) int {
+	for i := start + 1; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "## ") {
+			return i
+		}
+	}
+
+	return len(lines)
+}
+
+func capitalize(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+
+	return hash
+}
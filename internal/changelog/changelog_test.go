@@ -0,0 +1,280 @@
+package changelog_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/julianwyz/git-do/internal/changelog"
+	"github.com/julianwyz/git-do/internal/git"
+)
+
+func TestParse(t *testing.T) {
+	for name, tc := range map[string]struct {
+		commit      git.LogEntry
+		expected    changelog.Entry
+		categorized bool
+	}{
+		"feat": {
+			commit: git.LogEntry{
+				Hash:    "abc",
+				Subject: "feat(cli): add the changelog command",
+				Body:    "Some details.\n\nCloses: https://example.com/issues/1",
+			},
+			expected: changelog.Entry{
+				Hash:     "abc",
+				Category: changelog.CategoryAdded,
+				Scope:    "cli",
+				Summary:  "add the changelog command",
+				Closes:   []string{"https://example.com/issues/1"},
+			},
+			categorized: true,
+		},
+		"breaking chore": {
+			commit: git.LogEntry{
+				Subject: "chore!: drop support for go 1.24",
+				Body:    "BREAKING CHANGE: go 1.25 is required",
+			},
+			expected: changelog.Entry{
+				Category:      changelog.CategoryChanged,
+				Summary:       "drop support for go 1.24",
+				Breaking:      true,
+				BreakingNotes: []string{"go 1.25 is required"},
+			},
+			categorized: true,
+		},
+		"omitted": {
+			commit: git.LogEntry{
+				Subject: "docs: fix typo",
+			},
+			expected: changelog.Entry{
+				Category: changelog.CategoryOmitted,
+				Summary:  "fix typo",
+			},
+			categorized: true,
+		},
+		"not conventional": {
+			commit: git.LogEntry{
+				Subject: "Add the changelog command",
+			},
+			expected: changelog.Entry{
+				Summary: "Add the changelog command",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			entry, categorized := changelog.Parse(tc.commit)
+			if categorized != tc.categorized {
+				t.Fatalf("expected categorized to be %v", tc.categorized)
+			}
+
+			if entry.Hash != tc.expected.Hash ||
+				entry.Category != tc.expected.Category ||
+				entry.Scope != tc.expected.Scope ||
+				entry.Summary != tc.expected.Summary ||
+				entry.Breaking != tc.expected.Breaking ||
+				!slices.Equal(entry.BreakingNotes, tc.expected.BreakingNotes) ||
+				!slices.Equal(entry.Closes, tc.expected.Closes) {
+				t.Fatalf("unexpected entry: %+v", entry)
+			}
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	release := changelog.Release{
+		Version: "v1.2.0",
+		Date:    time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		Entries: []changelog.Entry{
+			{Hash: "1111111111", Category: changelog.CategoryFixed, Summary: "fix the thing", Closes: []string{"#2"}},
+			{Hash: "2222222222", Category: changelog.CategoryAdded, Summary: "add a thing"},
+			{Hash: "3333333333", Category: changelog.CategoryOmitted, Summary: "tidy up"},
+			{
+				Hash:          "4444444444",
+				Category:      changelog.CategoryAdded,
+				Scope:         "api",
+				Summary:       "replace the thing",
+				Breaking:      true,
+				BreakingNotes: []string{"the thing is gone"},
+			},
+		},
+	}
+
+	expected := `## [1.2.0] - 2026-10-17
+
+### Added
+
+- **BREAKING:** **api:** Replace the thing (4444444)
+  - the thing is gone
+- Add a thing (2222222)
+
+### Fixed
+
+- Fix the thing (1111111), closes #2
+`
+	if release.String() != expected {
+		t.Fatalf("unexpected release:\n%s", release.String())
+	}
+}
+
+func TestInsert(t *testing.T) {
+	date := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	entries := []changelog.Entry{
+		{Category: changelog.CategoryAdded, Summary: "add a thing"},
+	}
+
+	t.Run("new", func(t *testing.T) {
+		expected := changelog.Header + `
+## [Unreleased]
+
+### Added
+
+- Add a thing
+`
+		if out := changelog.Insert("", changelog.Release{Entries: entries}); out != expected {
+			t.Fatalf("unexpected changelog:\n%s", out)
+		}
+	})
+
+	existing := changelog.Header + `
+## [Unreleased]
+
+### Fixed
+
+- Fix a thing
+
+## [1.0.0] - 2026-01-01
+
+### Added
+
+- Start
+`
+
+	t.Run("version", func(t *testing.T) {
+		expected := changelog.Header + `
+## [Unreleased]
+
+### Fixed
+
+- Fix a thing
+
+## [1.1.0] - 2026-10-17
+
+### Added
+
+- Add a thing
+
+## [1.0.0] - 2026-01-01
+
+### Added
+
+- Start
+`
+		out := changelog.Insert(existing, changelog.Release{
+			Version: "1.1.0",
+			Date:    date,
+			Entries: entries,
+		})
+		if out != expected {
+			t.Fatalf("unexpected changelog:\n%s", out)
+		}
+	})
+
+	t.Run("unreleased", func(t *testing.T) {
+		expected := changelog.Header + `
+## [Unreleased]
+
+### Added
+
+- Add a thing
+
+## [1.0.0] - 2026-01-01
+
+### Added
+
+- Start
+`
+		if out := changelog.Insert(existing, changelog.Release{Entries: entries}); out != expected {
+			t.Fatalf("unexpected changelog:\n%s", out)
+		}
+	})
+}
+
+func TestParseVersion(t *testing.T) {
+	v, ok := changelog.ParseVersion("v1.2.3")
+	if !ok || v.Prefix != "v" || v.Major != 1 || v.Minor != 2 || v.Patch != 3 {
+		t.Fatalf("unexpected version: %+v", v)
+	}
+
+	for _, tag := range []string{"1.2", "v1.2.3-rc.1", "release-1.2.3"} {
+		if _, ok := changelog.ParseVersion(tag); ok {
+			t.Fatalf("expected %q to be invalid", tag)
+		}
+	}
+
+	for b, expected := range map[changelog.Bump]string{
+		changelog.BumpMajor: "v2.0.0",
+		changelog.BumpMinor: "v1.3.0",
+		changelog.BumpPatch: "v1.2.4",
+	} {
+		if next := v.Next(b).String(); next != expected {
+			t.Fatalf("expected %s bump to be %s, got %s", b, expected, next)
+		}
+	}
+}
+
+func TestSuggestBump(t *testing.T) {
+	var (
+		fix      = changelog.Entry{Category: changelog.CategoryFixed, Summary: "fix"}
+		chore    = changelog.Entry{Category: changelog.CategoryOmitted, Summary: "chore"}
+		feat     = changelog.Entry{Category: changelog.CategoryAdded, Summary: "feat"}
+		breaking = changelog.Entry{Category: changelog.CategoryChanged, Summary: "break", Breaking: true}
+	)
+
+	for name, tc := range map[string]struct {
+		current  changelog.Version
+		entries  []changelog.Entry
+		expected changelog.Bump
+		reasons  []string
+	}{
+		"patch": {
+			current:  changelog.Version{Major: 1},
+			entries:  []changelog.Entry{fix, chore},
+			expected: changelog.BumpPatch,
+			reasons:  []string{"fix"},
+		},
+		"minor": {
+			current:  changelog.Version{Major: 1},
+			entries:  []changelog.Entry{fix, feat},
+			expected: changelog.BumpMinor,
+			reasons:  []string{"feat"},
+		},
+		"major": {
+			current:  changelog.Version{Major: 1},
+			entries:  []changelog.Entry{breaking, feat},
+			expected: changelog.BumpMajor,
+			reasons:  []string{"break"},
+		},
+		"major version zero": {
+			entries:  []changelog.Entry{breaking, feat},
+			expected: changelog.BumpMinor,
+			reasons:  []string{"break", "feat"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, reasons := changelog.SuggestBump(tc.current, tc.entries)
+			if b != tc.expected {
+				t.Fatalf("expected a %s bump, got %s", tc.expected, b)
+			}
+
+			var summaries []string
+			for _, r := range reasons {
+				summaries = append(summaries, r.Summary)
+			}
+
+			if !slices.Equal(summaries, tc.reasons) {
+				t.Fatalf("unexpected reasons: %v", summaries)
+			}
+		})
+	}
+}
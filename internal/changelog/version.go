@@ -0,0 +1,117 @@
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type (
+	// Version of a release, following semantic versioning
+	// without pre-release or build metadata.
+	Version struct {
+		// Prefix of the version's tag, e.g. "v".
+		Prefix string
+		Major  int
+		Minor  int
+		Patch  int
+	}
+
+	// Bump of a version's component.
+	Bump int
+)
+
+const (
+	BumpPatch Bump = iota
+	BumpMinor
+	BumpMajor
+)
+
+var versionPattern = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)$`)
+
+// ParseVersion of a tag, e.g. "v1.2.3".
+func ParseVersion(tag string) (Version, bool) {
+	m := versionPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return Version{}, false
+	}
+
+	returner := Version{Prefix: m[1]}
+	for i, dst := range []*int{&returner.Major, &returner.Minor, &returner.Patch} {
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return Version{}, false
+		}
+
+		*dst = n
+	}
+
+	return returner, true
+}
+
+func (recv Version) String() string {
+	return fmt.Sprintf("%s%d.%d.%d", recv.Prefix, recv.Major, recv.Minor, recv.Patch)
+}
+
+// Next version, bumping the component and resetting those following it.
+func (recv Version) Next(b Bump) Version {
+	switch b {
+	case BumpMajor:
+		return Version{Prefix: recv.Prefix, Major: recv.Major + 1}
+	case BumpMinor:
+		return Version{Prefix: recv.Prefix, Major: recv.Major, Minor: recv.Minor + 1}
+	default:
+		return Version{Prefix: recv.Prefix, Major: recv.Major, Minor: recv.Minor, Patch: recv.Patch + 1}
+	}
+}
+
+func (recv Bump) String() string {
+	switch recv {
+	case BumpMajor:
+		return "major"
+	case BumpMinor:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// SuggestBump of the current version for a release of the entries,
+// along with the entries that require it.
+//
+// Breaking changes and removals require a major release, additions
+// and deprecations a minor release and anything else a patch. While
+// the major version is zero, breaking changes are a minor release.
+func SuggestBump(current Version, entries []Entry) (Bump, []Entry) {
+	var (
+		returner = BumpPatch
+		reasons  []Entry
+	)
+
+	for _, e := range entries {
+		var b Bump
+		switch {
+		case e.Breaking, e.Category == CategoryRemoved:
+			b = BumpMajor
+			if current.Major == 0 {
+				b = BumpMinor
+			}
+		case e.Category == CategoryAdded, e.Category == CategoryDeprecated:
+			b = BumpMinor
+		case e.Category == CategoryOmitted:
+			continue
+		default:
+			b = BumpPatch
+		}
+
+		switch {
+		case b > returner:
+			returner = b
+			reasons = []Entry{e}
+		case b == returner:
+			reasons = append(reasons, e)
+		}
+	}
+
+	return returner, reasons
+}
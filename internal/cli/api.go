@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/julianwyz/git-do/internal/apidiff"
+	"github.com/julianwyz/git-do/internal/git"
+	"github.com/rs/zerolog/log"
+)
+
+// committingBreaks of the Go API by the changes that
+// `git commit` will commit, given the scope.
+func committingBreaks(ctx *Ctx, scope git.CommitScope) []string {
+	return describeBreaks(scopeBreaks(ctx, scope))
+}
+
+// scopeBreaks of the Go API between HEAD and the
+// changes that `git commit` will commit, given the scope.
+func scopeBreaks(ctx *Ctx, scope git.CommitScope) ([]apidiff.Break, error) {
+	paths, err := git.CommittingFiles(ctx, ctx.WorkingDir, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasGoFiles(paths) {
+		return nil, nil
+	}
+
+	var before apidiff.Tree
+	if head, err := git.RevParse(ctx, ctx.WorkingDir, "HEAD"); err == nil {
+		// otherwise, nothing has been committed yet
+		tree, err := git.TreeAt(ctx, ctx.WorkingDir, head)
+		if err != nil {
+			return nil, err
+		}
+
+		before = tree
+	}
+
+	var after *git.Tree
+	if scope.Staged() {
+		after, err = git.IndexTree(ctx, ctx.WorkingDir)
+	} else {
+		after, err = git.WorkTree(ctx, ctx.WorkingDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return apidiff.Compare(before, after, paths)
+}
+
+// revisionBreaks of the Go API between the revisions. An
+// empty from compares against an empty tree.
+func revisionBreaks(ctx *Ctx, from, to string) ([]apidiff.Break, error) {
+	paths, err := git.ChangedFiles(ctx, ctx.WorkingDir, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasGoFiles(paths) {
+		return nil, nil
+	}
+
+	var before apidiff.Tree
+	if len(from) > 0 {
+		tree, err := git.TreeAt(ctx, ctx.WorkingDir, from)
+		if err != nil {
+			return nil, err
+		}
+
+		before = tree
+	}
+
+	after, err := git.TreeAt(ctx, ctx.WorkingDir, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return apidiff.Compare(before, after, paths)
+}
+
+// commitBreaks of the Go API by the commit.
+func commitBreaks(ctx *Ctx, ref string) []string {
+	// the root commit has no parent to compare against
+	parent, _ := git.RevParse(ctx, ctx.WorkingDir, ref+"^")
+
+	return describeBreaks(revisionBreaks(ctx, parent, ref))
+}
+
+// describeBreaks for the LLM. A failed analysis is logged
+// and ignored, as it is only ever a hint.
+func describeBreaks(breaks []apidiff.Break, err error) []string {
+	if err != nil {
+		log.Debug().
+			Err(err).
+			Msg("unable to analyze api changes")
+
+		return nil
+	}
+
+	described := make([]string, 0, len(breaks))
+	for _, b := range breaks {
+		described = append(described, b.String())
+	}
+
+	return described
+}
+
+func hasGoFiles(paths []string) bool {
+	return slices.ContainsFunc(paths, func(p string) bool {
+		return strings.HasSuffix(p, ".go")
+	})
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/julianwyz/git-do/internal/cache"
+)
+
+type (
+	Cache struct {
+		Clear CacheClear `cmd:""`
+	}
+
+	CacheClear struct{}
+)
+
+const (
+	cacheHelp = `git do cache <clear>
+=======
+
+Manage the cache of LLM responses. Responses are cached in ` + "`$HOME/.gitdo/cache`" + `, keyed by the model, API base, instructions, context and changes of each request, so repeating an identical request (e.g. after a failed ` + "`pre-commit`" + ` hook) is free.
+
+Regenerating a commit message during review always makes a new request. Any command may bypass the cache with the global ` + "`--no-cache`" + ` flag (e.g. ` + "`git do --no-cache commit`" + `).
+
+Flags:
+
+` + "`-h`" + `, ` + "`--help`" + `
+> Show this help message.
+
+Commands:
+
+` + "`clear`" + `
+> Remove every cached response.
+`
+)
+
+func (recv Cache) Help(dst io.Writer) error {
+	return renderHelpMarkdown(dst, cacheHelp)
+}
+
+func (recv *CacheClear) Run(ctx *Ctx) error {
+	c, err := cache.New(cacheDir(ctx.HomeDir))
+	if err != nil {
+		return err
+	}
+
+	n, err := c.Clear()
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "Removed %d cached responses.\n", n)
+
+	return nil
+}
+
+func cacheDir(homeDir string) string {
+	return filepath.Join(homeDir, ".gitdo", "cache")
+}
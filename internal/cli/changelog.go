@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/julianwyz/git-do/internal/changelog"
+	"github.com/julianwyz/git-do/internal/git"
+)
+
+type (
+	Changelog struct {
+		Version string `placeholder:"<version>"`
+		GroupBy string `name:"group-by" enum:"auto,conventional,llm" default:"auto" placeholder:"<grouping>"`
+		Write   bool
+		File    string `default:"CHANGELOG.md" placeholder:"<path>"`
+		Range   string `arg:"" optional:""`
+	}
+)
+
+const (
+	groupByAuto         = "auto"
+	groupByConventional = "conventional"
+	groupByLLM          = "llm"
+
+	changelogHelp = `git do changelog [flags] [range]
+=======
+
+Write a [Keep a Changelog](https://keepachangelog.com) section of the commits in a range, grouping them into ` + "`Added`" + `, ` + "`Changed`" + `, ` + "`Deprecated`" + `, ` + "`Removed`" + `, ` + "`Fixed`" + ` and ` + "`Security`" + ` changes. Merge commits are not included.
+
+Breaking changes (marked by ` + "`!`" + ` or a ` + "`BREAKING CHANGE`" + ` footer) are highlighted and listed first, and the issues of any ` + "`Closes:`" + ` trailers are referenced.
+
+Flags:
+
+` + "`-h`" + `, ` + "`--help`" + `
+> Show this help message.
+
+` + "`--version <version>`" + `
+> The version of the section's heading, dated today. If omitted, the section is ` + "`Unreleased`" + `.
+
+` + "`--group-by <grouping>`" + `
+> How commits are grouped:
+>
+> ` + "`conventional`" + `: by their conventional commit type (e.g. ` + "`feat`" + ` is ` + "`Added`" + `). Other commits are ` + "`Changed`" + `.
+>
+> ` + "`llm`" + `: by a category assigned by the LLM.
+>
+> ` + "`auto`" + ` (the default): by their conventional commit type where possible, otherwise by a category assigned by the LLM.
+>
+> Commits that are not notable to users (e.g. ` + "`chore`" + ` or ` + "`docs`" + `) are omitted, unless they are breaking.
+
+` + "`--write`" + `
+> Insert the section into the changelog file, rather than printing it. An existing ` + "`Unreleased`" + ` section is replaced by an unreleased section, and kept above a versioned one.
+
+` + "`--file <path>`" + `
+> The changelog file to write. Defaults to ` + "`CHANGELOG.md`" + `.
+
+Arguments:
+
+` + "`[range]`" + `
+> The range of commits, in any form accepted by ` + "`git log`" + ` (e.g. ` + "`v0.3.0..HEAD`" + `). If omitted, the commits since the latest tag are used, or every commit if there are no tags.
+`
+)
+
+func (recv *Changelog) Run(ctx *Ctx) error {
+	commitRange := recv.Range
+	if len(commitRange) == 0 {
+		commitRange = "HEAD"
+		if tag, err := git.LatestTag(ctx, ctx.WorkingDir, "HEAD"); err == nil {
+			commitRange = tag + "..HEAD"
+		}
+	}
+
+	commits, err := git.LogEntries(
+		ctx,
+		ctx.WorkingDir,
+		"--no-merges",
+		commitRange,
+	)
+	if err != nil {
+		return err
+	}
+
+	entries, err := categorizeCommits(ctx, commits, recv.GroupBy)
+	if err != nil {
+		return err
+	}
+
+	release := changelog.Release{
+		Version: recv.Version,
+		Date:    time.Now(),
+		Entries: entries,
+	}
+
+	if !recv.Write {
+		_, err := io.WriteString(ctx.Output, release.String())
+
+		return err
+	}
+
+	path := recv.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ctx.WorkingDir, path)
+	}
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.WriteFile(
+		path,
+		[]byte(changelog.Insert(string(existing), release)),
+		0644,
+	); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "Updated %s with %d commits.\n", recv.File, len(commits))
+
+	return nil
+}
+
+// categorizeCommits into entries of the changelog,
+// grouping them as described by groupBy.
+func categorizeCommits(ctx *Ctx, commits []git.LogEntry, groupBy string) ([]changelog.Entry, error) {
+	var (
+		entries   = make([]changelog.Entry, 0, len(commits))
+		uncertain []int
+		messages  []string
+	)
+
+	for _, c := range commits {
+		entry, categorized := changelog.Parse(c)
+
+		switch {
+		case groupBy == groupByLLM,
+			!categorized && groupBy != groupByConventional:
+			uncertain = append(uncertain, len(entries))
+			messages = append(messages, c.Subject+"\n\n"+c.Body)
+		case !categorized:
+			entry.Category = changelog.CategoryChanged
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(uncertain) == 0 {
+		return entries, nil
+	}
+
+	categories := make([]string, 0, len(changelog.Categories)+1)
+	for _, c := range slices.Concat(changelog.Categories, []changelog.Category{changelog.CategoryOmitted}) {
+		categories = append(categories, string(c))
+	}
+
+	assigned, err := ctx.LLM.CategorizeCommits(ctx, messages, categories)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, at := range uncertain {
+		category := changelog.Category(assigned[i])
+		switch {
+		case len(category) == 0:
+			// the model skipped it
+			category = changelog.CategoryChanged
+		case category == changelog.CategoryOmitted && entries[at].Breaking:
+			category = changelog.CategoryChanged
+		}
+
+		entries[at].Category = category
+	}
+
+	return entries, nil
+}
+
+func (recv Changelog) Help(dst io.Writer) error {
+	return renderHelpMarkdown(dst, changelogHelp)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"os"
@@ -12,16 +13,26 @@ import (
 	"github.com/julianwyz/git-do/internal/config"
 	"github.com/julianwyz/git-do/internal/credentials"
 	"github.com/julianwyz/git-do/internal/llm"
+	"github.com/julianwyz/git-do/internal/redact"
 	"golang.org/x/text/language"
 )
 
 type (
 	CLI struct {
-		Help    Help    `cmd:""`
-		Commit  Commit  `cmd:""`
-		Explain Explain `cmd:""`
-		Status  Status  `cmd:""`
-		Init    Init    `cmd:""`
+		Help      Help      `cmd:""`
+		Commit    Commit    `cmd:""`
+		Explain   Explain   `cmd:""`
+		Status    Status    `cmd:""`
+		Init      Init      `cmd:""`
+		Hook      Hook      `cmd:""`
+		Split     Split     `cmd:""`
+		Cache     Cache     `cmd:""`
+		Changelog Changelog `cmd:""`
+		Release   Release   `cmd:""`
+		PR        PR        `cmd:""`
+
+		// NoCache bypasses the cache of LLM responses.
+		NoCache bool `name:"no-cache"`
 
 		runner *kong.Context `kong:"-"`
 		config *cliConfig
@@ -31,6 +42,7 @@ type (
 		context.Context
 		LLM         *llm.LLM
 		UserConfig  *config.Config
+		Redactor    *redact.Redactor
 		Output      destination
 		Input       destination
 		HomeDir     string
@@ -47,11 +59,31 @@ type (
 	}
 )
 
-const Version = "0.3.1"
+const (
+	Version = "0.3.1"
+
+	// ExitCodeSecretFound when a secret is found in
+	// the changes and the redact mode is "block".
+	ExitCodeSecretFound = 3
+)
 
 var (
-	ErrNoProjectConfig = errors.New("cli: no project config file found")
-	ErrNoCreds         = errors.New("cli: no user credentials found")
+	ErrNoProjectConfig  = errors.New("cli: no project config file found")
+	ErrNoCreds          = errors.New("cli: no user credentials found")
+	ErrAborted          = errors.New("cli: aborted by user")
+	ErrAlreadyPushed    = errors.New("cli: commit has already been pushed")
+	ErrNotOnBranch      = errors.New("cli: commit is not on the current branch")
+	ErrRewordArgs       = errors.New("cli: a reworded commit cannot be amended or passed args for git commit")
+	ErrUnknownPair      = errors.New("cli: unknown pair alias")
+	ErrDiffFilters      = errors.New("cli: commit filters cannot be used when explaining a diff")
+	ErrAPIFormat        = errors.New("cli: api changes cannot be formatted as json")
+	ErrNothingToRelease = errors.New("cli: no commits since the latest release")
+	ErrInvalidVersion   = errors.New("cli: version is not a semantic version")
+	ErrDirtyWorktree    = errors.New("cli: working tree has uncommitted changes")
+	ErrNoBase           = errors.New("cli: branch has no upstream, provide a base with --base")
+	ErrNoBranchCommits  = errors.New("cli: no commits on the branch since its base")
+	ErrLinesWithoutFile = errors.New("cli: a line range can only be explained with --file")
+	ErrFilePaths        = errors.New("cli: paths cannot be used when explaining a file")
 )
 
 func New(opts ...CLIOpt) (*CLI, error) {
@@ -84,10 +116,18 @@ func New(opts ...CLIOpt) (*CLI, error) {
 }
 
 func (recv *CLI) Exec(ctx context.Context) error {
-	var llmDriver *llm.LLM
+	var (
+		llmDriver     *llm.LLM
+		projectConfig *config.Config
+		redactor      *redact.Redactor
+	)
 
 	if recv.configsRequired(recv.runner.Command()) {
-		projectConfig, apiCredentials, err := recv.loadConfig()
+		var (
+			apiCredentials *credentials.Credentials
+			err            error
+		)
+		projectConfig, apiCredentials, err = recv.loadConfig()
 		if err != nil {
 			return err
 		}
@@ -98,11 +138,20 @@ func (recv *CLI) Exec(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+
+		redactor, err = projectConfig.Redactor(
+			redact.WithReporter(os.Stderr),
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	return recv.runner.Run(&Ctx{
 		Context:     ctx,
 		LLM:         llmDriver,
+		UserConfig:  projectConfig,
+		Redactor:    redactor,
 		HomeDir:     recv.config.hd,
 		WorkingDir:  recv.config.wd,
 		Input:       recv.config.input,
@@ -112,6 +161,22 @@ func (recv *CLI) Exec(ctx context.Context) error {
 	})
 }
 
+// Redacted sequence of patches, with any secrets handled
+// according to the configured mode.
+func (recv *Ctx) Redacted(seq iter.Seq2[string, error]) iter.Seq2[string, error] {
+	if recv.Redactor == nil {
+		return seq
+	}
+
+	return recv.Redactor.Seq(seq)
+}
+
+// Interactive reports whether both the input and output
+// are attached to a terminal.
+func (recv *Ctx) Interactive() bool {
+	return !recv.PipedInput && !recv.PipedOutput
+}
+
 func (recv *CLI) isOutputBeingPiped() bool {
 	o, _ := recv.config.output.Stat()
 
@@ -134,6 +199,23 @@ func (recv *CLI) configureLLM(
 		llm.WithHTTPClient(recv.config.httpClient),
 	}
 
+	customFormat, err := cfg.CustomFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	if customFormat != nil {
+		opts = append(opts, llm.WithCustomFormat(*customFormat))
+	}
+
+	if cfg.Commit != nil && cfg.Commit.Retries != nil {
+		opts = append(opts, llm.WithValidationRetries(*cfg.Commit.Retries))
+	}
+
+	if cfg.Commit != nil && cfg.Commit.Scopes != nil {
+		opts = append(opts, llm.WithAllowedScopes(cfg.Commit.Scopes.Allowed...))
+	}
+
 	if len(cfg.Language) > 0 {
 		tag, err := language.Parse(cfg.Language)
 		if err != nil {
@@ -148,8 +230,22 @@ func (recv *CLI) configureLLM(
 		if len(cfg.LLM.APIBase) > 0 {
 			opts = append(opts, llm.WithAPIBase(cfg.LLM.APIBase))
 		}
+		model := llm.DefaultModel
 		if len(cfg.LLM.Model) > 0 {
-			opts = append(opts, llm.WithModel(cfg.LLM.Model))
+			model = cfg.LLM.Model
+			opts = append(opts, llm.WithModel(model))
+		}
+
+		if cfg.LLM.Budget != nil {
+			strategy := cfg.LLM.Budget.Strategy
+			if len(strategy) == 0 {
+				strategy = llm.BudgetStrategyStat
+			}
+
+			opts = append(opts, llm.WithTokenBudget(
+				cfg.LLM.Budget.TokensFor(model),
+				strategy,
+			))
 		}
 
 		if cfg.LLM.Reasoning != nil {
@@ -161,6 +257,17 @@ func (recv *CLI) configureLLM(
 		}
 	}
 
+	if !recv.NoCache {
+		rc, err := cfg.ResponseCache(cacheDir(recv.config.hd))
+		if err != nil {
+			return nil, err
+		}
+
+		if rc != nil {
+			opts = append(opts, llm.WithCache(rc))
+		}
+	}
+
 	if creds != nil {
 		if len(creds.APIKey) > 0 {
 			opts = append(opts, llm.WithAPIKey(creds.APIKey))
@@ -207,7 +314,7 @@ func (recv *CLI) loadConfig() (
 
 func (recv *CLI) configsRequired(cmd string) bool {
 	switch cmd {
-	case "init", "help":
+	case "init", "help", "hook install", "hook uninstall", "cache clear":
 		return false
 	}
 
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"iter"
 	"os"
 	"slices"
 	"strings"
 
+	"github.com/alecthomas/kong"
+	"github.com/charmbracelet/huh"
 	"github.com/julianwyz/git-do/internal/git"
 	"github.com/julianwyz/git-do/internal/llm"
 	"github.com/rs/zerolog/log"
@@ -15,11 +18,32 @@ import (
 
 type (
 	Commit struct {
-		Resolves []string `short:"r"`
-		Message  []string `short:"m"`
-		Amend    bool
-		Trailer  bool     `default:"true" negatable:""`
-		Args     []string `arg:"" optional:"" passthrough:"all"`
+		Resolves       []string `short:"r"`
+		Pair           []string `placeholder:"<alias>"`
+		BranchResolves bool     `default:"true" negatable:""`
+		Message        []string `short:"m"`
+		Amend          bool
+		Trailer        bool   `default:"true" negatable:""`
+		Review         bool   `default:"true" negatable:""`
+		Print          bool   `aliases:"dry-run"`
+		Output         string `type:"path"`
+		Candidates     int    `default:"1"`
+		Reword         string `placeholder:"<ref>"`
+		Force          bool
+		Args           []string `arg:"" optional:"" passthrough:"all"`
+
+		// changes described by the generated message,
+		// the staged changes when nil
+		changes func() (iter.Seq2[string, error], error)
+		// pairs credited as co-authors
+		pairs *pairing
+		// issues resolved by the changes, retrieved once
+		// and reused for each regeneration
+		issues []llm.Issue
+		// target commit being amended or reworded
+		target string
+		// breaks of the Go API by the changes, analyzed once
+		breaks []string
 	}
 )
 
@@ -36,12 +60,44 @@ Flags:
 ` + "`-r=<id>...`" + `, ` + "`--resolves=<id>...`" + `
 > Issue or ticket identifiers that are resolved by the content of this commit. This flag may be included more than once or as a comma-separated list.
 
+` + "`--[no-]branch-resolves`" + `
+> Include, or omit, the resolutions derived from the current branch name by the ` + "`[commit.resolutions]`" + ` config (they will be included by default).
+
 ` + "`--amend`" + `
 > Re-generate the most recent commit's message and amend that commit.
 
+` + "`--reword=<ref>`" + `
+> Re-generate the message of the commit at ` + "`<ref>`" + ` and rewrite it in place. Any commits that follow it on the current branch are replayed on top of the reworded commit; their content and authorship are unchanged.
+>
+> Commits that have already been pushed to the upstream branch are refused. It cannot be combined with ` + "`--amend`" + ` or any input for the ` + "`git commit`" + ` CLI.
+
+` + "`--force`" + `
+> Reword a commit even if it has already been pushed to the upstream branch.
+
 ` + "`--[no-]trailer`" + `
 > Include, or omit, the ` + "`Message-generated-by`" + ` commit trailer (it will be included by default).
 
+` + "`--pair=<alias>...`" + `
+> Credit members of the ` + "`[team]`" + ` roster as co-authors with ` + "`Co-authored-by`" + ` trailers. This flag may be included more than once or as a comma-separated list.
+>
+> When reviewing, the co-authors may be changed. Pairs you have recently committed with in this repository are listed first.
+
+` + "`--[no-]review`" + `
+> Review the generated message before committing (enabled by default). The message may be accepted, edited in your configured git editor, regenerated with additional guidance or aborted.
+>
+> Review is skipped when input or output is being piped.
+
+` + "`--print`" + `, ` + "`--dry-run`" + `
+> Write the generated message to ` + "`stdout`" + ` instead of committing.
+
+` + "`--output=<file>`" + `
+> Write the generated message to ` + "`<file>`" + ` instead of committing.
+
+` + "`--candidates=<n>`" + `
+> Generate ` + "`<n>`" + ` alternative messages in a single request and pick one of them.
+>
+> When input or output is being piped, the alternatives are written as a numbered list and no commit is made.
+
 ` + "`-m=<msg>...`" + `, ` + "`--message=<msg>...`" + `
 > A message that will be included in the commit generation prompt. This message may be used to alter, inform or fully override the default system prompt.
 >
@@ -52,21 +108,28 @@ Flags:
 ---
 
 **All input provided after the above set of flags will be piped directly to the ` + "`git commit`" + ` CLI.**
+
+The generated message describes the changes that ` + "`git commit`" + ` will commit, so options such as ` + "`-a`" + `, ` + "`--include`" + `, ` + "`--only`" + ` and pathspecs are respected.
 `
 )
 
-func (recv *Commit) Run(ctx *Ctx) error {
-	if recv.Amend {
-		return recv.amendCommit(ctx)
+// newCommit with the default value of each of its flags, as
+// if the command were run without any.
+func newCommit() (*Commit, error) {
+	commit := &Commit{}
+	parser, err := kong.New(commit)
+	if err != nil {
+		return nil, err
 	}
 
-	seq, err := git.ListStaged(
-		ctx, ctx.WorkingDir,
-	)
-	if err != nil {
-		return err
+	if _, err := parser.Parse(nil); err != nil {
+		return nil, err
 	}
 
+	return commit, nil
+}
+
+func (recv *Commit) Run(ctx *Ctx) error {
 	if ctx.PipedInput {
 		msg, err := io.ReadAll(os.Stdin)
 		if err != nil {
@@ -76,73 +139,485 @@ func (recv *Commit) Run(ctx *Ctx) error {
 		recv.Message = slices.Insert(recv.Message, 0, string(msg))
 	}
 
-	instructionOverride := strings.Join(recv.Message, "\n")
-	commitMsg, err := ctx.LLM.GenerateCommit(
-		ctx, seq,
-		llm.CommitWithResolutions(recv.Resolves...),
-		llm.CommitWithInstructions(instructionOverride),
-	)
+	pairs, err := newPairing(ctx, recv.Pair)
 	if err != nil {
 		return err
 	}
+	recv.pairs = pairs
 
-	if recv.Trailer {
-		commitMsg += fmt.Sprintf("\n\n%s",
-			recv.commitTrailer(ctx),
+	commit := func(msg string) error {
+		return git.Commit(
+			ctx,
+			ctx.WorkingDir,
+			bytes.NewBufferString(msg),
+			recv.Args...,
 		)
 	}
 
-	log.Debug().Msgf("commit msg:\n%s", commitMsg)
+	switch {
+	case len(recv.Reword) > 0:
+		if recv.Amend || len(recv.Args) > 0 {
+			// the commit is rewritten without running `git commit`
+			return ErrRewordArgs
+		}
 
-	return git.Commit(
-		ctx,
-		ctx.WorkingDir,
-		bytes.NewBufferString(commitMsg),
-		recv.Args...,
-	)
+		ref, err := recv.rewordTarget(ctx)
+		if err != nil {
+			return err
+		}
+
+		recv.target = ref
+		recv.changes = commitChanges(ctx, ref)
+		commit = func(msg string) error {
+			return git.Reword(
+				ctx,
+				ctx.WorkingDir,
+				ref,
+				bytes.NewBufferString(msg),
+			)
+		}
+	case recv.Amend:
+		headRef, err := git.HeadHash(ctx, ctx.WorkingDir)
+		if err != nil {
+			return err
+		}
+
+		recv.target = headRef
+		// the amended commit replaces HEAD, along
+		// with whatever the args add to it
+		recv.changes = func() (iter.Seq2[string, error], error) {
+			return git.ListAmending(
+				ctx, ctx.WorkingDir,
+				git.ParseCommitScope(recv.Args),
+			)
+		}
+		commit = func(msg string) error {
+			return git.Commit(
+				ctx,
+				ctx.WorkingDir,
+				bytes.NewBufferString(msg),
+				slices.Concat([]string{"--amend"}, recv.Args)...,
+			)
+		}
+	}
+
+	if recv.Candidates > 1 && !ctx.Interactive() {
+		// there is no way to pick a candidate
+		return recv.listCandidates(ctx)
+	}
+
+	printOnly := recv.Print || len(recv.Output) > 0
+	if printOnly {
+		// the message is destined for somewhere other than
+		// a commit, it can be reviewed there
+		recv.Review = false
+	}
+
+	commitMsg, err := recv.generateMessage(ctx)
+	if err != nil {
+		return err
+	}
+
+	if printOnly {
+		return recv.printMessage(ctx, commitMsg)
+	}
+
+	if err := commit(commitMsg); err != nil {
+		return err
+	}
+
+	recv.pairs.remember(ctx)
+
+	return nil
 }
 
 func (recv Commit) Help(dst io.Writer) error {
 	return renderHelpMarkdown(dst, commitHelp)
 }
 
-func (recv *Commit) amendCommit(ctx *Ctx) error {
-	headRef, err := git.HeadHash(ctx, ctx.WorkingDir)
+// generateMessage generates the complete commit message,
+// including any trailers, for the changes being committed.
+func (recv *Commit) generateMessage(ctx *Ctx) (string, error) {
+	generate := func(opts ...llm.CommitOpt) (string, error) {
+		seq, err := recv.listChanges(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return ctx.LLM.GenerateCommit(
+			ctx, seq,
+			append(recv.generationOpts(ctx), opts...)...,
+		)
+	}
+
+	var (
+		commitMsg string
+		err       error
+	)
+	if recv.Candidates > 1 {
+		commitMsg, err = recv.pickCandidate(ctx)
+	} else {
+		commitMsg, err = generate()
+	}
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	seq, err := git.ListCommitChanges(
+	if recv.Review && ctx.Interactive() {
+		commitMsg, err = reviewMessage(
+			ctx, commitMsg,
+			func(guidance string) (string, error) {
+				if len(strings.TrimSpace(guidance)) > 0 {
+					recv.Message = append(recv.Message, guidance)
+				}
+
+				// a cached message would be the same as the last
+				return generate(llm.CommitWithRefresh())
+			},
+			recv.pairs,
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var trailers []string
+	if recv.Trailer {
+		trailers = append(trailers, generatedTrailer(ctx))
+	}
+
+	if recv.pairs != nil {
+		trailers = append(trailers, recv.pairs.trailers()...)
+	}
+
+	if len(trailers) > 0 {
+		commitMsg, err = git.InterpretTrailers(
+			ctx, ctx.WorkingDir,
+			commitMsg,
+			trailers...,
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.Debug().Msgf("commit msg:\n%s", commitMsg)
+
+	return commitMsg, nil
+}
+
+func (recv *Commit) generationOpts(ctx *Ctx) []llm.CommitOpt {
+	resolutions := recv.Resolves
+	if recv.BranchResolves {
+		for _, r := range branchResolutions(ctx) {
+			if !slices.Contains(resolutions, r) {
+				resolutions = append(resolutions, r)
+			}
+		}
+	}
+
+	if recv.issues == nil {
+		recv.issues = append([]llm.Issue{}, lookupIssues(ctx, resolutions)...)
+	}
+
+	return slices.Concat(
+		historyExamples(ctx),
+		pathScope(ctx, recv.committingFiles),
+		[]llm.CommitOpt{
+			llm.CommitWithResolutions(resolutions...),
+			llm.CommitWithIssues(recv.issues...),
+			llm.CommitWithBreakingChanges(recv.apiBreaks(ctx)...),
+			llm.CommitWithInstructions(strings.Join(recv.Message, "\n")),
+		},
+	)
+}
+
+// committingFiles changed by the commit being made, amended or reworded.
+func (recv *Commit) committingFiles(ctx *Ctx) ([]string, error) {
+	var files []string
+	if len(recv.target) > 0 {
+		// the root commit has no parent to compare against
+		parent, _ := git.RevParse(ctx, ctx.WorkingDir, recv.target+"^")
+
+		changed, err := git.ChangedFiles(ctx, ctx.WorkingDir, parent, recv.target)
+		if err != nil || !recv.Amend {
+			return changed, err
+		}
+
+		files = changed
+	}
+
+	committing, err := git.CommittingFiles(
 		ctx, ctx.WorkingDir,
-		headRef,
+		git.ParseCommitScope(recv.Args),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	commitMsg, err := ctx.LLM.GenerateCommit(
+	for _, f := range committing {
+		if !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+// apiBreaks of the Go API by the changes being committed.
+func (recv *Commit) apiBreaks(ctx *Ctx) []string {
+	if recv.breaks != nil {
+		return recv.breaks
+	}
+
+	var breaks []string
+	if len(recv.target) > 0 {
+		breaks = commitBreaks(ctx, recv.target)
+	} else {
+		breaks = committingBreaks(ctx, git.ParseCommitScope(recv.Args))
+	}
+
+	recv.breaks = append([]string{}, breaks...)
+
+	return recv.breaks
+}
+
+// listChanges being committed. Unless amending or rewording, these
+// are determined by the args passed through to `git commit`.
+func (recv *Commit) listChanges(ctx *Ctx) (iter.Seq2[string, error], error) {
+	list := recv.changes
+	if list == nil {
+		list = func() (iter.Seq2[string, error], error) {
+			return git.ListCommitting(
+				ctx, ctx.WorkingDir,
+				git.ParseCommitScope(recv.Args),
+			)
+		}
+	}
+
+	seq, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.Redacted(seq), nil
+}
+
+func (recv *Commit) generateCandidates(ctx *Ctx) ([]string, error) {
+	seq, err := recv.listChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx.LLM.GenerateCommitCandidates(
 		ctx, seq,
-		llm.CommitWithResolutions(recv.Resolves...),
-		llm.CommitWithInstructions(strings.Join(recv.Message, "\n")),
+		recv.Candidates,
+		recv.generationOpts(ctx)...,
 	)
+}
+
+func (recv *Commit) pickCandidate(ctx *Ctx) (string, error) {
+	candidates, err := recv.generateCandidates(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	opts := make([]huh.Option[string], len(candidates))
+	for i, c := range candidates {
+		title, _, _ := strings.Cut(strings.TrimSpace(c), "\n")
+		opts[i] = huh.NewOption(title, c)
+	}
+
+	var picked string
+	if err := huh.NewSelect[string]().
+		Title("Which message should be used?").
+		Options(opts...).
+		Value(&picked).
+		Run(); err != nil {
+		return "", err
+	}
+
+	return picked, nil
+}
+
+func (recv *Commit) listCandidates(ctx *Ctx) error {
+	candidates, err := recv.generateCandidates(ctx)
 	if err != nil {
 		return err
 	}
 
-	args := slices.Concat(
-		recv.Args,
-		[]string{"--amend"},
-	)
+	for i, c := range candidates {
+		if _, err := fmt.Fprintf(
+			ctx.Output,
+			"%d. %s\n\n",
+			i+1,
+			strings.ReplaceAll(strings.TrimSpace(c), "\n", "\n   "),
+		); err != nil {
+			return err
+		}
+	}
 
-	return git.Commit(
+	return nil
+}
+
+func (recv *Commit) printMessage(ctx *Ctx, msg string) error {
+	msg = strings.TrimSpace(msg) + "\n"
+
+	if len(recv.Output) > 0 {
+		return os.WriteFile(recv.Output, []byte(msg), 0644)
+	}
+
+	_, err := ctx.Output.WriteString(msg)
+
+	return err
+}
+
+// rewordTarget resolves the commit being reworded, ensuring
+// that it can be safely rewritten.
+func (recv *Commit) rewordTarget(ctx *Ctx) (string, error) {
+	ref, err := git.RevParse(ctx, ctx.WorkingDir, recv.Reword)
+	if err != nil {
+		return "", err
+	}
+
+	onBranch, err := git.IsAncestor(ctx, ctx.WorkingDir, ref, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	if !onBranch {
+		return "", ErrNotOnBranch
+	}
+
+	if recv.Force {
+		return ref, nil
+	}
+
+	upstream, err := git.Upstream(ctx, ctx.WorkingDir)
+	if err != nil || len(upstream) == 0 {
+		return ref, err
+	}
+
+	pushed, err := git.IsAncestor(ctx, ctx.WorkingDir, ref, upstream)
+	if err != nil {
+		return "", err
+	}
+
+	if pushed {
+		return "", ErrAlreadyPushed
+	}
+
+	return ref, nil
+}
+
+func commitChanges(ctx *Ctx, ref string) func() (iter.Seq2[string, error], error) {
+	return func() (iter.Seq2[string, error], error) {
+		return git.ListCommitChanges(
+			ctx, ctx.WorkingDir,
+			ref,
+		)
+	}
+}
+
+// branchResolutions referenced by the name of the current
+// branch, when enabled by the project config.
+func branchResolutions(ctx *Ctx) []string {
+	if ctx.UserConfig == nil ||
+		ctx.UserConfig.Commit == nil ||
+		ctx.UserConfig.Commit.Resolutions == nil {
+		return nil
+	}
+
+	branch, err := git.CurrentBranch(ctx, ctx.WorkingDir)
+	if err != nil || len(branch) == 0 {
+		return nil
+	}
+
+	resolutions, err := ctx.UserConfig.Commit.Resolutions.FromBranch(branch)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to derive resolutions from branch")
+
+		return nil
+	}
+
+	log.Debug().
+		Str("branch", branch).
+		Strs("resolutions", resolutions).
+		Msg("derived resolutions from branch")
+
+	return resolutions
+}
+
+// historyExamples of the repository's commit message style,
+// when enabled by the project config.
+//
+// Messages generated by git-do are excluded, so that it
+// does not learn from its own output.
+func historyExamples(ctx *Ctx) []llm.CommitOpt {
+	if ctx.UserConfig == nil ||
+		ctx.UserConfig.Commit == nil ||
+		ctx.UserConfig.Commit.History == nil {
+		return nil
+	}
+
+	history := ctx.UserConfig.Commit.History
+	msgs, err := git.RecentMessages(
 		ctx,
 		ctx.WorkingDir,
-		bytes.NewBufferString(commitMsg),
-		args...,
+		history.Limit(),
+		messageGenTrailerName,
+		history.Authors,
+		history.Paths,
 	)
+	if err != nil {
+		// a repo without any commits has no history to learn from
+		log.Debug().Err(err).Msg("failed to load commit history")
+
+		return nil
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	return []llm.CommitOpt{
+		llm.CommitWithExamples(msgs...),
+	}
+}
+
+// pathScope of the changed files, as mapped by the
+// `[commit.scopes]` config.
+func pathScope(ctx *Ctx, files func(*Ctx) ([]string, error)) []llm.CommitOpt {
+	if ctx.UserConfig == nil ||
+		ctx.UserConfig.Commit == nil ||
+		ctx.UserConfig.Commit.Scopes == nil {
+		return nil
+	}
+
+	changed, err := files(ctx)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to list changed files for scope")
+
+		return nil
+	}
+
+	scope, found := ctx.UserConfig.Commit.Scopes.For(changed)
+	if !found {
+		return nil
+	}
+
+	log.Debug().
+		Str("scope", scope).
+		Msg("derived scope from paths")
+
+	return []llm.CommitOpt{
+		llm.CommitWithScope(scope),
+	}
 }
 
-func (recv *Commit) commitTrailer(ctx *Ctx) string {
+// generatedTrailer identifies the git-do version, API
+// and model used to generate a commit message.
+func generatedTrailer(ctx *Ctx) string {
 	components := [][]string{
 		{
 			"git-do",
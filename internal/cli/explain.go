@@ -1,22 +1,45 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
+	"iter"
+	"slices"
+	"strings"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/julianwyz/git-do/internal/git"
+	"github.com/julianwyz/git-do/internal/llm"
 )
 
 type (
 	Explain struct {
-		From  string `arg:"" optional:""`
-		To    string `arg:"" optional:""`
-		Plain bool   `optional:""`
+		Plain       bool     `optional:""`
+		Format      string   `enum:"markdown,json" default:"markdown" placeholder:"<format>"`
+		API         bool     `name:"api" xor:"api"`
+		Staged      bool     `xor:"changes"`
+		Worktree    bool     `xor:"changes"`
+		Diff        bool     `xor:"changes,api"`
+		File        string   `placeholder:"<path>" xor:"changes,api"`
+		Lines       string   `short:"L" placeholder:"<range>"`
+		Since       string   `placeholder:"<date>"`
+		Until       string   `placeholder:"<date>"`
+		Author      []string `placeholder:"<pattern>"`
+		Grep        []string `placeholder:"<pattern>"`
+		FirstParent bool     `name:"first-parent"`
+		NoMerges    bool     `name:"no-merges"`
+		Revisions   []string `arg:"" optional:"" passthrough:"partial"`
 	}
 )
 
 const (
-	explainHelp = `git do explain [ref] [to-ref]
+	explainFormatJSON = "json"
+
+	explainHelp = `git do explain [flags] [revisions...] [-- paths...]
+git do explain (--staged | --worktree) [flags] [paths...]
+git do explain --diff [flags] [commit] [commit] [-- paths...]
+git do explain --file <path> [-L <range>] [flags] [revisions...]
 =======
 
 Flags:
@@ -27,75 +50,359 @@ Flags:
 ` + "`--plain`" + `
 > Output the explanation without markdown rendering.
 
-Arguments:
+` + "`--format <format>`" + `
+> The format of the explanation, either ` + "`markdown`" + ` (the default) or ` + "`json`" + `.
+>
+> ` + "`json`" + ` outputs an object with a ` + "`summary`" + ` of the changes, a summary of each of the ` + "`commits`" + ` (by ` + "`hash`" + `), the affected ` + "`areas`" + `, the ` + "`user_facing_changes`" + `, ` + "`risks`" + ` to be aware of and the referenced ` + "`issues`" + `.
+
+` + "`--api`" + `
+> List the changes that break the exported API of Go packages, rather than explaining the commits. Packages are compared before the oldest and after the newest commit; tests, ` + "`main`" + ` and ` + "`internal`" + ` packages are not part of the API.
+
+` + "`--staged`" + `
+> Explain the staged changes, rather than commits.
+
+` + "`--worktree`" + `
+> Explain all changes to tracked files in the working tree since ` + "`HEAD`" + `, staged or not, rather than commits.
+
+` + "`--diff`" + `
+> Explain the changes between commits, rather than the commits themselves. The arguments are passed to ` + "`git diff`" + ` (e.g. ` + "`main feature`" + ` or ` + "`v1.0...HEAD`" + `).
+
+` + "`--file <path>`" + `
+> Explain how and why the file evolved, citing the commits involved. The file's history is followed across renames (as with ` + "`git log --follow`" + `), and the commits are limited by the flags and revisions below.
+
+` + "`-L`" + `, ` + "`--lines <range>`" + `
+> With ` + "`--file`" + `, only explain the history of a range of the file's lines, in any form accepted by ` + "`git log -L`" + ` (e.g. ` + "`10,20`" + ` or ` + "`:funcname`" + `).
 
-` + "`[ref]`" + `
-> The commit reference to explain. If omitted, ` + "`HEAD`" + ` is used.
+` + "`--since <date>`" + `, ` + "`--until <date>`" + `
+> Only explain commits more recent, or older, than the date.
 
-` + "`[to-ref]`" + `
-> The lower-bound commit reference to explain.
+` + "`--author <pattern>`" + `
+> Only explain commits whose author matches the pattern. May be repeated.
+
+` + "`--grep <pattern>`" + `
+> Only explain commits whose message matches the pattern. May be repeated.
+
+` + "`--first-parent`" + `
+> Only follow the first parent of merge commits, explaining each merge as the changes it brought in.
+
+` + "`--no-merges`" + `
+> Do not explain merge commits.
+
+Arguments:
+
+` + "`[revisions...]`" + `
+> The commits to explain, in any form accepted by ` + "`git log`" + ` (e.g. ` + "`v1.0..HEAD`" + `, ` + "`main...feature`" + ` or ` + "`^v1.0 HEAD`" + `). The commits explained are exactly those ` + "`git log`" + ` reports.
 >
-> All commits between ` + "`[ref]`" + `and ` + "`[to-ref]`" + ` will be included in the explanation.
+> A single revision explains only that commit. If omitted, ` + "`HEAD`" + ` is explained.
 >
-> If omitted, only the ` + "`[ref]`" + ` is explained.
+> The flags above must precede the revisions; anything following the first revision is passed to ` + "`git log`" + ` verbatim.
+
+` + "`[-- paths...]`" + `
+> Only explain commits changing the paths, and only their changes to the paths.
+
+` + "`[paths...]`" + `
+> With ` + "`--staged`" + ` or ` + "`--worktree`" + `, only explain the changes to the paths.
 `
 )
 
 func (recv *Explain) Run(ctx *Ctx) error {
-	commitRefs := make([]string, 0, 2)
-	if len(recv.From) > 0 {
-		commitRefs = append(commitRefs, recv.From)
-		if len(recv.To) > 0 {
-			commitRefs = append(commitRefs, recv.To)
-		}
-	} else {
-		commitRefs = append(commitRefs, "HEAD")
+	if recv.API && recv.Format == explainFormatJSON {
+		return ErrAPIFormat
+	}
+
+	if len(recv.Lines) > 0 && len(recv.File) == 0 {
+		return ErrLinesWithoutFile
+	}
+
+	if len(recv.File) > 0 {
+		return recv.explainFile(ctx)
+	}
+
+	if recv.comparing() {
+		return recv.explainDiff(ctx)
+	}
+
+	if recv.API {
+		return recv.explainAPI(ctx)
 	}
 
-	commitIter, err := git.CommitsBetween(
+	commitIter, err := git.ListCommits(
 		ctx,
 		ctx.WorkingDir,
-		commitRefs,
+		recv.logArgs()...,
 	)
 	if err != nil {
 		return err
 	}
 
+	commits, err := collect(ctx.Redacted(commitIter))
+	if err != nil {
+		return err
+	}
+
+	return recv.explainCommits(ctx, commits)
+}
+
+// explainCommits with the issues they reference.
+func (recv *Explain) explainCommits(ctx *Ctx, commits []string, opts ...llm.ExplainOpt) error {
+	opts = append(opts, llm.ExplainWithIssues(
+		lookupIssues(ctx, issueReferences(commits))...,
+	))
+
+	if recv.Format == explainFormatJSON {
+		return writeJSON(ctx.Output, func() (*llm.Explanation, error) {
+			return ctx.LLM.ExplainCommitsStructured(ctx, sequence(commits), opts...)
+		})
+	}
+
+	return recv.render(ctx, func(dst io.Writer) error {
+		return ctx.LLM.ExplainCommits(ctx, sequence(commits), dst, opts...)
+	})
+}
+
+// explainFile explains the history of the file, or of its lines.
+func (recv *Explain) explainFile(ctx *Ctx) error {
+	if slices.Contains(recv.Revisions, "--") {
+		return ErrFilePaths
+	}
+
+	history, err := git.FileHistory(
+		ctx,
+		ctx.WorkingDir,
+		recv.File,
+		recv.Lines,
+		slices.Concat(recv.filterArgs(), recv.Revisions)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	commits, err := collect(ctx.Redacted(history))
+	if err != nil {
+		return err
+	}
+
+	if len(commits) == 0 {
+		_, _ = fmt.Fprintf(ctx.Output, "No commits changed %s.\n", recv.File)
+
+		return nil
+	}
+
+	return recv.explainCommits(ctx, commits, llm.ExplainWithFile(recv.File, recv.Lines))
+}
+
+// comparing reports whether changes, rather than commits, are explained.
+func (recv *Explain) comparing() bool {
+	return recv.Staged || recv.Worktree || recv.Diff
+}
+
+// explainDiff explains the staged, working tree or diffed changes.
+func (recv *Explain) explainDiff(ctx *Ctx) error {
+	if len(recv.Since) > 0 || len(recv.Until) > 0 ||
+		len(recv.Author) > 0 || len(recv.Grep) > 0 ||
+		recv.FirstParent || recv.NoMerges {
+		return ErrDiffFilters
+	}
+
 	var (
-		outputDst io.ReadWriteCloser = ctx.Output
-		finalize  func() error
+		source string
+		diffs  iter.Seq2[string, error]
+		err    error
+		paths  = recv.Revisions
 	)
-	if !recv.Plain && !ctx.PipedOutput {
-		renderer, err := glamour.NewTermRenderer(
-			glamour.WithAutoStyle(),
-			glamour.WithPreservedNewLines(),
+	if len(paths) > 0 && paths[0] == "--" {
+		paths = paths[1:]
+	}
+
+	switch {
+	case recv.Staged:
+		if recv.API {
+			return printScopeBreaks(ctx, git.CommitScope{})
+		}
+
+		source = "Staged changes that have not been committed yet."
+		diffs, err = git.ListDiff(
+			ctx,
+			ctx.WorkingDir,
+			slices.Concat([]string{"--cached", "--"}, paths)...,
 		)
-		if err != nil {
-			return err
+	case recv.Worktree:
+		if recv.API {
+			return printScopeBreaks(ctx, git.CommitScope{All: true})
 		}
 
-		outputDst = renderer
+		source = "Changes in the working tree, staged or not, that have not been committed yet."
+		diffs, err = git.ListWorktree(ctx, ctx.WorkingDir, paths...)
+	default:
+		source = fmt.Sprintf(
+			"The output of `git diff %s`.",
+			strings.Join(recv.Revisions, " "),
+		)
+		diffs, err = git.ListDiff(ctx, ctx.WorkingDir, recv.Revisions...)
+	}
+	if err != nil {
+		return err
+	}
+
+	changes, err := collect(ctx.Redacted(diffs))
+	if err != nil {
+		return err
+	}
 
-		finalize = func() error {
-			if err := outputDst.Close(); err != nil {
-				return err
-			}
+	if recv.Format == explainFormatJSON {
+		return writeJSON(ctx.Output, func() (*llm.Explanation, error) {
+			return ctx.LLM.ExplainDiffStructured(ctx, source, sequence(changes))
+		})
+	}
 
-			_, err = io.Copy(ctx.Output, outputDst)
+	return recv.render(ctx, func(dst io.Writer) error {
+		return ctx.LLM.ExplainDiff(ctx, source, sequence(changes), dst)
+	})
+}
 
-			return err
-		}
+// writeJSON of the structured explanation to dst.
+func writeJSON(dst io.Writer, explain func() (*llm.Explanation, error)) error {
+	explanation, err := explain()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(explanation)
+}
+
+// render the explanation written by explain as
+// markdown, unless plain output is requested.
+func (recv *Explain) render(ctx *Ctx, explain func(io.Writer) error) error {
+	if recv.Plain || ctx.PipedOutput {
+		return explain(ctx.Output)
+	}
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithAutoStyle(),
+		glamour.WithPreservedNewLines(),
+	)
+	if err != nil {
+		return err
 	}
 
-	if err := ctx.LLM.ExplainCommits(
-		ctx, commitIter,
-		outputDst,
-	); err != nil {
+	if err := explain(renderer); err != nil {
 		return err
 	}
 
-	if finalize != nil {
-		return finalize()
+	if err := renderer.Close(); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(ctx.Output, renderer)
+
+	return err
+}
+
+// filterArgs of `git log` limiting the commits to explain.
+func (recv *Explain) filterArgs() []string {
+	var args []string
+	if len(recv.Since) > 0 {
+		args = append(args, "--since="+recv.Since)
+	}
+	if len(recv.Until) > 0 {
+		args = append(args, "--until="+recv.Until)
+	}
+	for _, a := range recv.Author {
+		args = append(args, "--author="+a)
+	}
+	for _, g := range recv.Grep {
+		args = append(args, "--grep="+g)
+	}
+	if recv.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if recv.NoMerges {
+		args = append(args, "--no-merges")
+	}
+
+	return args
+}
+
+// logArgs of `git log` listing the commits to explain.
+func (recv *Explain) logArgs() []string {
+	args := recv.filterArgs()
+
+	revisions := recv.Revisions
+	options := revisions
+	if i := slices.Index(revisions, "--"); i >= 0 {
+		options = revisions[:i]
+	}
+
+	switch {
+	case len(args) == 0 && len(options) == 1 && isSingleRevision(options[0]):
+		// a lone revision is that commit, not its history
+		args = append(args, "--no-walk")
+	case len(revisions) == 0 && len(args) == 0:
+		revisions = []string{"--no-walk", "HEAD"}
+	}
+
+	return append(args, revisions...)
+}
+
+// isSingleRevision reports whether rev names a single commit,
+// rather than a range or an option of `git log`.
+func isSingleRevision(rev string) bool {
+	return !strings.HasPrefix(rev, "-") &&
+		!strings.HasPrefix(rev, "^") &&
+		!strings.Contains(rev, "..") &&
+		!strings.HasSuffix(rev, "^@")
+}
+
+// explainAPI lists the breaks of the Go API by the commits,
+// comparing the parent of the oldest commit to the newest.
+func (recv *Explain) explainAPI(ctx *Ctx) error {
+	hashes, err := git.CommitHashes(ctx, ctx.WorkingDir, recv.logArgs()...)
+	if err != nil {
+		return err
+	}
+
+	if len(hashes) == 0 {
+		_, _ = ctx.Output.WriteString("No commits to compare.\n")
+
+		return nil
+	}
+
+	to := hashes[0]
+	// the root commit has no parent to compare against
+	from, _ := git.RevParse(ctx, ctx.WorkingDir, hashes[len(hashes)-1]+"^")
+
+	breaks, err := revisionBreaks(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	return printBreaks(ctx, describeBreaks(breaks, nil))
+}
+
+// printScopeBreaks of the Go API by the changes that
+// `git commit` will commit, given the scope.
+func printScopeBreaks(ctx *Ctx, scope git.CommitScope) error {
+	breaks, err := scopeBreaks(ctx, scope)
+	if err != nil {
+		return err
+	}
+
+	return printBreaks(ctx, describeBreaks(breaks, nil))
+}
+
+// printBreaks of the Go API, one per line.
+func printBreaks(ctx *Ctx, breaks []string) error {
+	if len(breaks) == 0 {
+		_, _ = ctx.Output.WriteString("No breaking changes to the Go API were detected.\n")
+
+		return nil
+	}
+
+	for _, b := range breaks {
+		_, _ = fmt.Fprintf(ctx.Output, "- %s\n", b)
 	}
 
 	return nil
@@ -6,6 +6,7 @@ import (
 	"maps"
 	"slices"
 	"sort"
+	"strings"
 
 	"github.com/alecthomas/kong"
 	"github.com/charmbracelet/glamour"
@@ -21,10 +22,16 @@ type (
 
 var (
 	helpMap = map[string]helper{
-		"init":    Init{},
-		"status":  Status{},
-		"explain": Explain{},
-		"commit":  Commit{},
+		"init":      Init{},
+		"status":    Status{},
+		"explain":   Explain{},
+		"commit":    Commit{},
+		"hook":      Hook{},
+		"split":     Split{},
+		"cache":     Cache{},
+		"changelog": Changelog{},
+		"release":   Release{},
+		"pr":        PR{},
 	}
 )
 
@@ -46,7 +53,10 @@ func (recv Help) Help(dst io.Writer) error {
 
 func (recv *CLI) OutputHelp(to io.Writer) kong.HelpPrinter {
 	return func(options kong.HelpOptions, cli *kong.Context) error {
-		return helpOf(to, cli.Command())
+		// subcommands share the help of their parent
+		cmd, _, _ := strings.Cut(cli.Command(), " ")
+
+		return helpOf(to, cmd)
 	}
 }
 
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julianwyz/git-do/internal/git"
+)
+
+type (
+	Hook struct {
+		Install   HookInstall   `cmd:""`
+		Uninstall HookUninstall `cmd:""`
+		Run       HookRun       `cmd:""`
+	}
+
+	HookInstall   struct{}
+	HookUninstall struct{}
+
+	HookRun struct {
+		Name   string `arg:"" enum:"prepare-commit-msg"`
+		File   string `arg:""`
+		Source string `arg:"" optional:""`
+		SHA    string `arg:"" optional:""`
+	}
+)
+
+const (
+	prepareCommitMsgHook = "prepare-commit-msg"
+	// chainedHookSuffix is appended to the name of any pre-existing
+	// hook that is displaced by installing the git-do hook.
+	chainedHookSuffix = ".pre-gitdo"
+	hookMarker        = "# git-do managed hook"
+	// hookSecretExitCode must match ExitCodeSecretFound
+	hookSecretExitCode = "3"
+	hookScript         = `#!/bin/sh
+` + hookMarker + `
+# Installed by ` + "`git do hook install`" + `. Remove with ` + "`git do hook uninstall`" + `.
+
+chained="$(dirname "$0")/` + prepareCommitMsgHook + chainedHookSuffix + `"
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+
+git do hook run ` + prepareCommitMsgHook + ` "$@"
+status=$?
+if [ $status -eq ` + hookSecretExitCode + ` ]; then
+	# a secret was found in the changes, fail the commit
+	exit $status
+elif [ $status -ne 0 ]; then
+	echo "git-do: unable to generate a commit message" >&2
+fi
+
+exit 0
+`
+	hookHelp = `git do hook <install|uninstall|run>
+=======
+
+Manage the ` + "`git do`" + ` ` + "`prepare-commit-msg`" + ` hook. Once installed, any ` + "`git commit`" + ` that would open an editor (including IDE commit dialogs and ` + "`git commit -v`" + `) will be pre-filled with a generated message of the staged changes.
+
+The hook is installed in the repo's hooks directory, respecting ` + "`core.hooksPath`" + `. An existing ` + "`prepare-commit-msg`" + ` hook is preserved and will continue to run before ` + "`git do`" + `.
+
+Flags:
+
+` + "`-h`" + `, ` + "`--help`" + `
+> Show this help message.
+
+Commands:
+
+` + "`install`" + `
+> Install the ` + "`prepare-commit-msg`" + ` hook.
+
+` + "`uninstall`" + `
+> Remove the ` + "`prepare-commit-msg`" + ` hook and restore any hook it displaced.
+
+` + "`run prepare-commit-msg <file> [source] [sha]`" + `
+> Run the hook. This is invoked by ` + "`git`" + ` and is not typically run by hand.
+`
+)
+
+var (
+	ErrHookNotManaged = errors.New("cli: hook is not managed by git do")
+)
+
+func (recv Hook) Help(dst io.Writer) error {
+	return renderHelpMarkdown(dst, hookHelp)
+}
+
+func (recv *HookInstall) Run(ctx *Ctx) error {
+	hookPath, err := prepareCommitMsgPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	managed, err := isManagedHook(hookPath)
+	switch {
+	case err == nil && managed:
+		_, _ = ctx.Output.WriteString("Hook is already installed.\n")
+
+		return nil
+	case err == nil:
+		// an existing hook, keep it around to be chained
+		if err := os.Rename(
+			hookPath,
+			hookPath+chainedHookSuffix,
+		); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(ctx.Output, "Existing hook moved to: %s\n", hookPath+chainedHookSuffix)
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(hookPath), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(hookPath, []byte(hookScript), 0755); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "Installed hook to: %s\n", hookPath)
+
+	return nil
+}
+
+func (recv *HookUninstall) Run(ctx *Ctx) error {
+	hookPath, err := prepareCommitMsgPath(ctx)
+	if err != nil {
+		return err
+	}
+
+	managed, err := isManagedHook(hookPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		_, _ = ctx.Output.WriteString("Hook is not installed.\n")
+
+		return nil
+	case err != nil:
+		return err
+	case !managed:
+		return ErrHookNotManaged
+	}
+
+	if err := os.Remove(hookPath); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(hookPath + chainedHookSuffix); err == nil {
+		if err := os.Rename(
+			hookPath+chainedHookSuffix,
+			hookPath,
+		); err != nil {
+			return err
+		}
+
+		_, _ = ctx.Output.WriteString("Restored previous hook.\n")
+	}
+
+	_, _ = ctx.Output.WriteString("Uninstalled hook.\n")
+
+	return nil
+}
+
+func (recv *HookRun) Run(ctx *Ctx) error {
+	if len(recv.Source) > 0 {
+		// a message was already provided by a flag, template,
+		// merge, squash or an existing commit. Leave it be.
+		return nil
+	}
+
+	existing, err := os.ReadFile(recv.File)
+	if err != nil {
+		return err
+	}
+
+	commit, err := newCommit()
+	if err != nil {
+		return err
+	}
+
+	// git opens the message in an editor once the hook is done
+	commit.Review = false
+
+	commitMsg, err := commit.generateMessage(ctx)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(
+		recv.File,
+		[]byte(strings.TrimSpace(commitMsg)+"\n"+string(existing)),
+		0644,
+	)
+}
+
+func prepareCommitMsgPath(ctx *Ctx) (string, error) {
+	dir, err := git.HooksDir(ctx, ctx.WorkingDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, prepareCommitMsgHook), nil
+}
+
+func isManagedHook(hookPath string) (bool, error) {
+	content, err := os.ReadFile(hookPath)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(string(content), hookMarker), nil
+}
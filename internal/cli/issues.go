@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"iter"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/julianwyz/git-do/internal/credentials"
+	"github.com/julianwyz/git-do/internal/llm"
+	"github.com/julianwyz/git-do/internal/tracker"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// issueReferencePattern matches the issue references
+	// included in a commit message's trailers.
+	issueReferencePattern = regexp.MustCompile(
+		`(?m)^\s*(?:Closes|Fixes|Resolves):\s*(\S+)\s*$`,
+	)
+)
+
+// lookupIssues referenced by refs from the configured issue tracker.
+//
+// The tracker only enriches the prompt, so any failure to reach it is
+// logged and the issue is skipped, rather than preventing generation.
+func lookupIssues(ctx *Ctx, refs []string) []llm.Issue {
+	if len(refs) == 0 ||
+		ctx.UserConfig == nil ||
+		ctx.UserConfig.Tracker == nil {
+		return nil
+	}
+
+	cfg := ctx.UserConfig.Tracker
+	opts := []tracker.Opt{
+		tracker.WithBaseURL(cfg.URL),
+		tracker.WithProject(cfg.Project),
+		tracker.WithFields(cfg.TitleField, cfg.DescriptionField),
+	}
+
+	if creds, err := credentials.LoadTrackerFrom(
+		os.DirFS(ctx.HomeDir),
+		cfg.Domain(),
+	); err == nil {
+		opts = append(opts, tracker.WithCredentials(creds.Username, creds.Token))
+	}
+
+	t, err := tracker.New(cfg.Kind, opts...)
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to configure issue tracker")
+
+		return nil
+	}
+
+	var returner []llm.Issue
+	for _, ref := range refs {
+		issue, err := t.Issue(ctx, ref)
+		if err != nil {
+			log.Debug().
+				Err(err).
+				Str("ref", ref).
+				Msg("failed to retrieve issue")
+
+			continue
+		}
+
+		returner = append(returner, llm.Issue{
+			Ref:         ref,
+			Title:       issue.Title,
+			Description: issue.Description,
+		})
+	}
+
+	return returner
+}
+
+// issueReferences found in the commits.
+func issueReferences(commits []string) []string {
+	var returner []string
+	for _, c := range commits {
+		for _, m := range issueReferencePattern.FindAllStringSubmatch(c, -1) {
+			if !slices.Contains(returner, m[1]) {
+				returner = append(returner, m[1])
+			}
+		}
+	}
+
+	return returner
+}
+
+// collect the values of the sequence, stopping at the first error.
+func collect(seq iter.Seq2[string, error]) ([]string, error) {
+	var returner []string
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		returner = append(returner, v)
+	}
+
+	return returner, nil
+}
+
+// sequence of the values.
+func sequence(values []string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for _, v := range values {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/julianwyz/git-do/internal/config"
+	"github.com/julianwyz/git-do/internal/git"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	// pairing of the commit with members of the team.
+	pairing struct {
+		roster   config.Team
+		selected []string
+	}
+)
+
+const (
+	coAuthorTrailerName = "Co-authored-by"
+	// recentPairsFile, within the git directory, of the
+	// pairs most recently committed with.
+	recentPairsFile = "gitdo/pairs"
+	maxRecentPairs  = 10
+)
+
+// newPairing with the aliases, which must all be in the team roster.
+//
+// The personal roster is merged with the project's roster, with the
+// project taking precedence.
+func newPairing(ctx *Ctx, aliases []string) (*pairing, error) {
+	roster := config.Team{}
+
+	personal, err := config.LoadTeamFrom(os.DirFS(ctx.HomeDir))
+	switch {
+	case err == nil:
+		maps.Copy(roster, personal)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	if ctx.UserConfig != nil {
+		maps.Copy(roster, ctx.UserConfig.Team)
+	}
+
+	for _, a := range aliases {
+		if _, found := roster[a]; !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPair, a)
+		}
+	}
+
+	return &pairing{
+		roster:   roster,
+		selected: aliases,
+	}, nil
+}
+
+// trailers of the selected pairs.
+func (recv *pairing) trailers() []string {
+	returner := make([]string, 0, len(recv.selected))
+	for _, a := range recv.selected {
+		returner = append(returner,
+			coAuthorTrailerName+": "+recv.roster[a].CoAuthor(),
+		)
+	}
+
+	return returner
+}
+
+// choose the pairs from the roster. The most recently used
+// pairs of the repository are listed first.
+func (recv *pairing) choose(ctx *Ctx) error {
+	recent := recentPairs(ctx)
+	aliases := slices.SortedFunc(maps.Keys(recv.roster), func(a, b string) int {
+		ai, bi := slices.Index(recent, a), slices.Index(recent, b)
+		switch {
+		case ai == bi:
+			return strings.Compare(a, b)
+		case ai < 0:
+			return 1
+		case bi < 0:
+			return -1
+		}
+
+		return ai - bi
+	})
+
+	opts := make([]huh.Option[string], len(aliases))
+	for i, a := range aliases {
+		opts[i] = huh.NewOption(
+			a+" - "+recv.roster[a].CoAuthor(), a,
+		).Selected(slices.Contains(recv.selected, a))
+	}
+
+	return huh.NewMultiSelect[string]().
+		Title("Who are you pairing with?").
+		Options(opts...).
+		Value(&recv.selected).
+		Run()
+}
+
+// remember the selected pairs as the most recently
+// used pairs of the repository.
+func (recv *pairing) remember(ctx *Ctx) {
+	if len(recv.selected) == 0 {
+		return
+	}
+
+	recent := slices.Clone(recv.selected)
+	for _, a := range recentPairs(ctx) {
+		if !slices.Contains(recent, a) {
+			recent = append(recent, a)
+		}
+	}
+
+	p, err := git.GitPath(ctx, ctx.WorkingDir, recentPairsFile)
+	if err == nil {
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		err = os.WriteFile(p, []byte(strings.Join(
+			recent[:min(len(recent), maxRecentPairs)], "\n",
+		)+"\n"), 0644)
+	}
+
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to remember pairs")
+	}
+}
+
+// recentPairs of the repository, most recent first.
+func recentPairs(ctx *Ctx) []string {
+	p, err := git.GitPath(ctx, ctx.WorkingDir, recentPairsFile)
+	if err != nil {
+		return nil
+	}
+
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+
+	return strings.Fields(string(content))
+}
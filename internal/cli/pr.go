@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/julianwyz/git-do/internal/git"
+	"github.com/julianwyz/git-do/internal/llm"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	PR struct {
+		Base   string `placeholder:"<ref>"`
+		Format string `enum:"text,json" default:"text" placeholder:"<format>"`
+		Output string `short:"o" placeholder:"<path>"`
+	}
+)
+
+const (
+	prFormatJSON   = "json"
+	prTemplateName = "pull_request_template.md"
+
+	prHelp = `git do pr [flags]
+=======
+
+Generate the title and description of a pull request for the current branch, from the messages of its commits and their combined changes. Merge commits are not included.
+
+If the repo has a pull request template (` + "`pull_request_template.md`" + ` in the ` + "`.github`" + ` directory, the root of the repo or the ` + "`docs`" + ` directory, in any case), the description fills in its headings. Otherwise, the first template of a ` + "`PULL_REQUEST_TEMPLATE`" + ` directory in those places is used, preferring one named ` + "`pull_request_template.md`" + `.
+
+The output is meant to be piped into the CLI of any forge, e.g.:
+
+` + "```" + `
+git do pr --format=json > pr.json
+gh pr create --title "$(jq -r .title pr.json)" --body "$(jq -r .body pr.json)"
+` + "```" + `
+
+Flags:
+
+` + "`-h`" + `, ` + "`--help`" + `
+> Show this help message.
+
+` + "`--base <ref>`" + `
+> The ref the branch will be merged into. If omitted, the upstream of the current branch is used.
+
+` + "`--format <format>`" + `
+> The format of the pull request:
+>
+> ` + "`text`" + ` (the default): the title on the first line, followed by a blank line and the description in markdown.
+>
+> ` + "`json`" + `: an object with the ` + "`title`" + ` and ` + "`body`" + `.
+
+` + "`-o`" + `, ` + "`--output <path>`" + `
+> Write the pull request to a file, rather than printing it.
+`
+)
+
+var (
+	// prTemplateDirs searched for a pull request template,
+	// in the order GitHub gives them precedence.
+	prTemplateDirs = []string{".github", ".", "docs"}
+	// prTemplateSubdirs of multiple templates, within each
+	// of the prTemplateDirs.
+	prTemplateSubdirs = []string{"PULL_REQUEST_TEMPLATE", "pull_request_template"}
+)
+
+func (recv *PR) Run(ctx *Ctx) error {
+	base := recv.Base
+	if len(base) == 0 {
+		upstream, err := git.Upstream(ctx, ctx.WorkingDir)
+		if err != nil {
+			return err
+		}
+
+		if len(upstream) == 0 {
+			return ErrNoBase
+		}
+
+		base = upstream
+	}
+
+	mergeBase, err := git.MergeBase(ctx, ctx.WorkingDir, base, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	commits, err := git.LogEntries(
+		ctx,
+		ctx.WorkingDir,
+		"--no-merges",
+		"--reverse",
+		mergeBase+"..HEAD",
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(commits) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoBranchCommits, base)
+	}
+
+	messages := make([]string, len(commits))
+	for i, c := range commits {
+		messages[i] = strings.TrimSpace(c.Subject + "\n\n" + c.Body)
+	}
+
+	diffs, err := git.ListDiff(ctx, ctx.WorkingDir, mergeBase, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	changes, err := collect(ctx.Redacted(diffs))
+	if err != nil {
+		return err
+	}
+
+	pr, err := ctx.LLM.DescribePullRequest(
+		ctx,
+		ctx.Redacted(sequence(messages)),
+		sequence(changes),
+		prTemplate(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(recv.Output) == 0 {
+		return recv.write(ctx.Output, pr)
+	}
+
+	dst := recv.Output
+	if !filepath.IsAbs(dst) {
+		dst = filepath.Join(ctx.WorkingDir, dst)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if err := recv.write(f, pr); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "Wrote pull request of %d commits to %s.\n", len(commits), recv.Output)
+
+	return nil
+}
+
+func (recv PR) Help(dst io.Writer) error {
+	return renderHelpMarkdown(dst, prHelp)
+}
+
+// write the pull request to dst in the requested format.
+func (recv *PR) write(dst io.Writer, pr *llm.PullRequest) error {
+	if recv.Format == prFormatJSON {
+		enc := json.NewEncoder(dst)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(pr)
+	}
+
+	_, err := fmt.Fprintf(dst, "%s\n\n%s\n", pr.Title, pr.Body)
+
+	return err
+}
+
+// prTemplate of the repo at HEAD, or an empty
+// string if it does not have one.
+func prTemplate(ctx *Ctx) string {
+	tree, err := git.TreeAt(ctx, ctx.WorkingDir, "HEAD")
+	if err != nil {
+		log.Debug().Err(err).Msg("failed to list files for a pull request template")
+
+		return ""
+	}
+
+	// a single template takes precedence over a directory of them
+	var candidates []string
+	for _, dir := range prTemplateDirs {
+		for _, name := range tree.Files(dir) {
+			if isPRTemplate(name) {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+
+	for _, dir := range prTemplateDirs {
+		for _, sub := range prTemplateSubdirs {
+			var (
+				files = tree.Files(path.Join(dir, sub))
+				rest  []string
+			)
+			for _, name := range files {
+				switch {
+				case isPRTemplate(name):
+					candidates = append(candidates, name)
+				case strings.EqualFold(path.Ext(name), ".md"):
+					rest = append(rest, name)
+				}
+			}
+
+			candidates = append(candidates, rest...)
+		}
+	}
+
+	for _, name := range candidates {
+		content, err := tree.ReadFile(name)
+		if err != nil {
+			log.Debug().Err(err).Str("file", name).Msg("failed to read pull request template")
+
+			continue
+		}
+
+		return string(content)
+	}
+
+	return ""
+}
+
+// isPRTemplate reports whether the file is named
+// as GitHub's default pull request template.
+func isPRTemplate(name string) bool {
+	return strings.EqualFold(path.Base(name), prTemplateName)
+}
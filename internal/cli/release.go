@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/julianwyz/git-do/internal/changelog"
+	"github.com/julianwyz/git-do/internal/git"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	Release struct {
+		Yes     bool   `short:"y"`
+		Version string `placeholder:"<version>"`
+	}
+)
+
+const (
+	releaseHelp = `git do release [flags]
+=======
+
+Suggest the next semantic version of the project from the commits since the latest version tag (e.g. ` + "`v1.2.3`" + `), then create an annotated tag of ` + "`HEAD`" + ` for it.
+
+Breaking changes (marked by ` + "`!`" + ` or a ` + "`BREAKING CHANGE`" + ` footer) and removals are a major release, while the major version is above zero. Additions and deprecations are a minor release, and anything else a patch. Commits that are not conventional commits are categorized by the LLM, as with ` + "`git do changelog`" + `.
+
+The tag's message is a generated summary of the release.
+
+If a ` + "`[release]`" + ` ` + "`bump`" + ` command is configured, it is run before tagging with the new version in ` + "`$GITDO_VERSION`" + ` (e.g. ` + "`1.2.3`" + `) and its tag in ` + "`$GITDO_TAG`" + `. Any changes it makes are committed, with the tag as the message, and the commit is tagged instead.
+
+Flags:
+
+` + "`-h`" + `, ` + "`--help`" + `
+> Show this help message.
+
+` + "`-y`" + `, ` + "`--yes`" + `
+> Create the tag without asking for confirmation.
+>
+> When input or output is being piped and this flag is not provided, the suggested version is listed and no changes are made.
+
+` + "`--version <version>`" + `
+> Release this version, rather than the suggested one.
+`
+)
+
+func (recv *Release) Run(ctx *Ctx) error {
+	current, commitRange, err := latestVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	commits, err := git.LogEntries(
+		ctx,
+		ctx.WorkingDir,
+		"--no-merges",
+		commitRange,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(commits) == 0 {
+		return ErrNothingToRelease
+	}
+
+	entries, err := categorizeCommits(ctx, commits, groupByAuto)
+	if err != nil {
+		return err
+	}
+
+	bump, reasons := changelog.SuggestBump(current, entries)
+	next := current.Next(bump)
+
+	recv.describe(ctx, current, commitRange, len(commits), next, bump, reasons)
+
+	if len(recv.Version) > 0 {
+		override, ok := changelog.ParseVersion(recv.Version)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidVersion, recv.Version)
+		}
+
+		next = override
+	}
+
+	if !recv.Yes && !ctx.Interactive() {
+		return nil
+	}
+
+	bumpCmd := ""
+	if ctx.UserConfig != nil && ctx.UserConfig.Release != nil {
+		bumpCmd = ctx.UserConfig.Release.Bump
+	}
+
+	if len(bumpCmd) > 0 {
+		// the bump's changes are committed, so
+		// nothing else may be committed with them
+		dirty, err := git.CommittingFiles(ctx, ctx.WorkingDir, git.CommitScope{All: true})
+		if err != nil {
+			return err
+		}
+
+		if len(dirty) > 0 {
+			return ErrDirtyWorktree
+		}
+	}
+
+	if !recv.Yes {
+		confirmed := false
+		if err := huh.NewConfirm().
+			Title(fmt.Sprintf("Release %s?", next)).
+			Value(&confirmed).
+			Run(); err != nil {
+			return err
+		}
+
+		if !confirmed {
+			return ErrAborted
+		}
+	}
+
+	// summarize before bumping, so a failure of
+	// the LLM does not leave a bump without a tag
+	messages := make([]string, len(commits))
+	for i, c := range commits {
+		messages[i] = strings.TrimSpace(c.Subject + "\n\n" + c.Body)
+	}
+
+	summary, err := ctx.LLM.SummarizeRelease(ctx, next.String(), sequence(messages))
+	if err != nil {
+		return err
+	}
+
+	if len(summary) == 0 {
+		log.Debug().Msg("release summary is empty, using the tag as its message")
+		summary = next.String()
+	}
+
+	// the commit preceding the bump's, if it made one
+	var bumpedFrom string
+	if len(bumpCmd) > 0 {
+		head, err := git.HeadHash(ctx, ctx.WorkingDir)
+		if err != nil {
+			return err
+		}
+
+		committed, err := recv.bump(ctx, bumpCmd, next)
+		if err != nil {
+			return err
+		}
+
+		if committed {
+			bumpedFrom = head
+		}
+	}
+
+	if err := git.CreateTag(
+		ctx,
+		ctx.WorkingDir,
+		next.String(),
+		strings.NewReader(summary+"\n"),
+	); err != nil {
+		if len(bumpedFrom) > 0 {
+			// an untagged bump would be bumped again by
+			// the next release. The worktree was clean
+			// before the bump, so nothing else is lost
+			if resetErr := git.ResetHard(ctx, ctx.WorkingDir, bumpedFrom); resetErr != nil {
+				return errors.Join(err, resetErr)
+			}
+		}
+
+		return err
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "Created tag %s. Publish it with `git push origin %s`.\n", next, next)
+
+	return nil
+}
+
+func (recv Release) Help(dst io.Writer) error {
+	return renderHelpMarkdown(dst, releaseHelp)
+}
+
+// latestVersion tagged before HEAD, along with the range of commits
+// since it. Without a version tag, every commit is in the range.
+func latestVersion(ctx *Ctx) (changelog.Version, string, error) {
+	tags, err := git.Tags(ctx, ctx.WorkingDir, "HEAD")
+	if err != nil {
+		return changelog.Version{}, "", err
+	}
+
+	for _, tag := range tags {
+		if v, ok := changelog.ParseVersion(tag); ok {
+			return v, tag + "..HEAD", nil
+		}
+	}
+
+	return changelog.Version{Prefix: "v"}, "HEAD", nil
+}
+
+// describe the suggested version, and the commits that require it.
+func (recv *Release) describe(
+	ctx *Ctx,
+	current changelog.Version,
+	commitRange string,
+	commitCount int,
+	next changelog.Version,
+	bump changelog.Bump,
+	reasons []changelog.Entry,
+) {
+	if commitRange == "HEAD" {
+		_, _ = fmt.Fprintf(ctx.Output, "No previous release was found, %d commits are unreleased.\n", commitCount)
+	} else {
+		_, _ = fmt.Fprintf(ctx.Output, "Latest release is %s, %d commits are unreleased.\n", current, commitCount)
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "Suggested version: %s (%s)\n", next, bump)
+
+	if len(reasons) == 0 {
+		_, _ = fmt.Fprintln(ctx.Output, "\nNone of the commits are notable to users.")
+
+		return
+	}
+
+	_, _ = fmt.Fprintf(ctx.Output, "\nA %s release is suggested because of:\n", bump)
+	for _, r := range reasons {
+		_, _ = io.WriteString(ctx.Output, r.String())
+	}
+}
+
+// bump the versions of the project's files using the command,
+// committing the changes it makes. Reports whether it committed.
+func (recv *Release) bump(ctx *Ctx, command string, next changelog.Version) (bool, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = ctx.WorkingDir
+	cmd.Env = append(os.Environ(),
+		"GITDO_VERSION="+strings.TrimPrefix(next.String(), next.Prefix),
+		"GITDO_TAG="+next.String(),
+	)
+	cmd.Stdout = ctx.Output
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return false, err
+	}
+
+	changed, err := git.CommittingFiles(ctx, ctx.WorkingDir, git.CommitScope{All: true})
+	if err != nil {
+		return false, err
+	}
+
+	if len(changed) == 0 {
+		return false, nil
+	}
+
+	if err := git.Commit(
+		ctx,
+		ctx.WorkingDir,
+		strings.NewReader(next.String()),
+		"--all",
+	); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
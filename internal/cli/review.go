@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/julianwyz/git-do/internal/git"
+)
+
+type (
+	reviewAction int
+
+	// regenerateFunc produces a new commit message, taking into account
+	// the additional guidance provided by the user.
+	regenerateFunc func(guidance string) (string, error)
+)
+
+const (
+	reviewAccept reviewAction = iota
+	reviewEdit
+	reviewRegenerate
+	reviewPairs
+	reviewAbort
+)
+
+const reviewRule = "────────────────────────────────────────"
+
+// reviewMessage presents msg to the user until it has either been
+// accepted or the review was aborted.
+//
+// If pairs is provided, the co-authors of the commit may also be changed.
+//
+// The accepted message is returned.
+func reviewMessage(
+	ctx *Ctx,
+	msg string,
+	regenerate regenerateFunc,
+	pairs *pairing,
+) (string, error) {
+	for {
+		display := strings.TrimSpace(msg)
+		if pairs != nil && len(pairs.selected) > 0 {
+			display += "\n\n" + strings.Join(pairs.trailers(), "\n")
+		}
+
+		_, _ = fmt.Fprintf(ctx.Output, "%s\n%s\n%s\n",
+			reviewRule,
+			display,
+			reviewRule,
+		)
+
+		opts := []huh.Option[reviewAction]{
+			huh.NewOption("Accept and commit", reviewAccept),
+			huh.NewOption("Edit in $EDITOR", reviewEdit),
+			huh.NewOption("Regenerate with guidance", reviewRegenerate),
+		}
+		if pairs != nil && len(pairs.roster) > 0 {
+			opts = append(opts, huh.NewOption("Change co-authors", reviewPairs))
+		}
+		opts = append(opts, huh.NewOption("Abort", reviewAbort))
+
+		var action reviewAction
+		if err := huh.NewSelect[reviewAction]().
+			Title("What would you like to do with this message?").
+			Options(opts...).
+			Value(&action).
+			Run(); err != nil {
+			return "", err
+		}
+
+		switch action {
+		case reviewAccept:
+			return msg, nil
+		case reviewEdit:
+			edited, err := git.Edit(ctx, ctx.WorkingDir, msg)
+			if err != nil {
+				return "", err
+			}
+
+			if len(strings.TrimSpace(edited)) == 0 {
+				// same as git, an empty message aborts
+				return "", ErrAborted
+			}
+
+			msg = edited
+		case reviewRegenerate:
+			var guidance string
+			if err := huh.NewText().
+				Title("What should be different?").
+				Description("This will be included alongside any existing messages.").
+				Value(&guidance).
+				Run(); err != nil {
+				return "", err
+			}
+
+			regenerated, err := regenerate(guidance)
+			if err != nil {
+				return "", err
+			}
+
+			msg = regenerated
+		case reviewPairs:
+			if err := pairs.choose(ctx); err != nil {
+				return "", err
+			}
+		default:
+			return "", ErrAborted
+		}
+	}
+}
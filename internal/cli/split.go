@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/julianwyz/git-do/internal/git"
+	"github.com/julianwyz/git-do/internal/llm"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	Split struct {
+		Yes     bool     `short:"y"`
+		Message []string `short:"m"`
+		Trailer bool     `default:"true" negatable:""`
+	}
+)
+
+const (
+	splitHelp = `git do split [flags]
+=======
+
+Split the currently staged changes into a series of atomic commits.
+
+The staged hunks are grouped into logical changes, then each group is staged and committed in turn with a generated message. You will be asked to review each commit before it is made.
+
+If anything fails, or the split is aborted, the index is restored so that any changes that have not yet been committed remain staged.
+
+Flags:
+
+` + "`-h`" + `, ` + "`--help`" + `
+> Show this help message.
+
+` + "`-y`" + `, ` + "`--yes`" + `
+> Make each commit without asking for confirmation.
+>
+> When input or output is being piped and this flag is not provided, the proposed commits are listed and no changes are made.
+
+` + "`--[no-]trailer`" + `
+> Include, or omit, the ` + "`Message-generated-by`" + ` commit trailer (it will be included by default).
+
+` + "`-m=<msg>...`" + `, ` + "`--message=<msg>...`" + `
+> A message that will be included in each commit generation prompt.
+`
+)
+
+func (recv *Split) Run(ctx *Ctx) error {
+	hunks, err := git.StagedHunks(ctx, ctx.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	patches := make([]string, len(hunks))
+	for i, h := range hunks {
+		patches[i] = h.Described(ctx, ctx.WorkingDir)
+	}
+
+	// only what is sent is described and redacted,
+	// the hunks themselves are applied unchanged
+	patches, err = collect(ctx.Redacted(sequence(patches)))
+	if err != nil {
+		return err
+	}
+
+	groups, err := ctx.LLM.GroupChanges(ctx, patches)
+	if err != nil {
+		return err
+	}
+
+	if !recv.Yes && !ctx.Interactive() {
+		return recv.listGroups(ctx, hunks, groups)
+	}
+
+	originalIndex, err := git.WriteTree(ctx, ctx.WorkingDir)
+	if err != nil {
+		return err
+	}
+
+	if err := recv.commitGroups(ctx, hunks, groups); err != nil {
+		// leave anything that was not committed staged
+		if restoreErr := git.ReadTree(
+			ctx, ctx.WorkingDir, originalIndex,
+		); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (recv Split) Help(dst io.Writer) error {
+	return renderHelpMarkdown(dst, splitHelp)
+}
+
+func (recv *Split) commitGroups(
+	ctx *Ctx,
+	hunks []git.Hunk,
+	groups []llm.ChangeGroup,
+) error {
+	if err := git.ResetIndex(ctx, ctx.WorkingDir); err != nil {
+		return err
+	}
+
+	for i, g := range groups {
+		groupHunks := make([]git.Hunk, 0, len(g.Hunks))
+		for _, h := range g.Hunks {
+			groupHunks = append(groupHunks, hunks[h])
+		}
+
+		if err := git.ApplyCached(
+			ctx, ctx.WorkingDir,
+			bytes.NewBufferString(git.BuildPatch(groupHunks)),
+		); err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(ctx.Output, "\nCommit %d of %d: %s\n",
+			i+1, len(groups), g.Summary,
+		)
+
+		commitMsg, err := recv.groupMessage(ctx, g)
+		if err != nil {
+			return err
+		}
+
+		if err := git.Commit(
+			ctx,
+			ctx.WorkingDir,
+			bytes.NewBufferString(commitMsg),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (recv *Split) groupMessage(ctx *Ctx, g llm.ChangeGroup) (string, error) {
+	// only this group's changes are staged
+	breaks := committingBreaks(ctx, git.CommitScope{})
+	scope := pathScope(ctx, func(ctx *Ctx) ([]string, error) {
+		return git.CommittingFiles(ctx, ctx.WorkingDir, git.CommitScope{})
+	})
+	generate := func(guidance []string, opts ...llm.CommitOpt) (string, error) {
+		seq, err := git.ListStaged(
+			ctx, ctx.WorkingDir,
+		)
+		if err != nil {
+			return "", err
+		}
+
+		return ctx.LLM.GenerateCommit(
+			ctx, ctx.Redacted(seq),
+			slices.Concat(
+				historyExamples(ctx),
+				scope,
+				opts,
+				[]llm.CommitOpt{
+					llm.CommitWithBreakingChanges(breaks...),
+					llm.CommitWithInstructions(strings.Join(
+						slices.Concat(recv.Message, guidance),
+						"\n",
+					)),
+				},
+			)...,
+		)
+	}
+
+	commitMsg, err := generate(nil)
+	if err != nil {
+		return "", err
+	}
+
+	if !recv.Yes {
+		var guidance []string
+		commitMsg, err = reviewMessage(
+			ctx, commitMsg,
+			func(extra string) (string, error) {
+				if len(strings.TrimSpace(extra)) > 0 {
+					guidance = append(guidance, extra)
+				}
+
+				return generate(guidance, llm.CommitWithRefresh())
+			},
+			nil,
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if recv.Trailer {
+		commitMsg, err = git.InterpretTrailers(
+			ctx, ctx.WorkingDir,
+			commitMsg,
+			generatedTrailer(ctx),
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.Debug().
+		Str("group", g.Summary).
+		Msgf("commit msg:\n%s", commitMsg)
+
+	return commitMsg, nil
+}
+
+func (recv *Split) listGroups(
+	ctx *Ctx,
+	hunks []git.Hunk,
+	groups []llm.ChangeGroup,
+) error {
+	for i, g := range groups {
+		var files []string
+		for _, h := range g.Hunks {
+			if f := hunks[h].File; !slices.Contains(files, f) {
+				files = append(files, f)
+			}
+		}
+
+		if _, err := fmt.Fprintf(
+			ctx.Output,
+			"%d. %s\n   %s\n\n",
+			i+1,
+			g.Summary,
+			strings.Join(files, ", "),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -42,7 +42,7 @@ func (recv *Status) Run(ctx *Ctx) error {
 	}
 
 	err = ctx.LLM.ExplainStatus(
-		ctx, status, seq,
+		ctx, status, ctx.Redacted(seq),
 		ctx.Output,
 	)
 
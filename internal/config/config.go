@@ -1,23 +1,42 @@
 package config
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"maps"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/julianwyz/git-do/internal/cache"
 	"github.com/julianwyz/git-do/internal/git"
 	"github.com/julianwyz/git-do/internal/llm"
+	"github.com/julianwyz/git-do/internal/redact"
+	"github.com/julianwyz/git-do/internal/tracker"
 )
 
 type (
 	Config struct {
-		Version  string  `toml:"version"`
-		Language string  `toml:"language"`
-		LLM      *LLM    `toml:"llm"`
-		Commit   *Commit `toml:"commit"`
+		Version  string   `toml:"version"`
+		Language string   `toml:"language"`
+		LLM      *LLM     `toml:"llm"`
+		Commit   *Commit  `toml:"commit"`
+		Tracker  *Tracker `toml:"tracker"`
+		Redact   *Redact  `toml:"redact"`
+		Cache    *Cache   `toml:"cache"`
+		Release  *Release `toml:"release"`
+		// Team roster, by alias, of possible pairs.
+		Team Team `toml:"team,omitempty"`
 
 		configFs fs.FS
 	}
@@ -27,6 +46,13 @@ type (
 		Model     string     `toml:"model"`
 		Context   *Context   `toml:"context"`
 		Reasoning *Reasoning `toml:"reasoning"`
+		Budget    *Budget    `toml:"budget"`
+	}
+
+	Budget struct {
+		Tokens   int                `toml:"tokens"`
+		Strategy llm.BudgetStrategy `toml:"strategy"`
+		Models   map[string]int     `toml:"models"`
 	}
 
 	Reasoning struct {
@@ -34,18 +60,125 @@ type (
 	}
 
 	Commit struct {
-		Format git.CommitFormat `toml:"format"`
+		Format  git.CommitFormat   `toml:"format"`
+		Formats map[string]*Format `toml:"formats"`
+		// Retries of a generated message that violates its format.
+		Retries     *int         `toml:"retries"`
+		History     *History     `toml:"history"`
+		Resolutions *Resolutions `toml:"resolutions"`
+		Scopes      *Scopes      `toml:"scopes"`
+	}
+
+	// Scopes of conventional commits.
+	Scopes struct {
+		// Allowed scopes. If empty, any scope is allowed.
+		Allowed []string `toml:"allowed"`
+		// Paths, as globs, mapped to the scope of their changes.
+		// A "**" matches any number of directories.
+		Paths map[string]string `toml:"paths"`
+	}
+
+	// Resolutions derived from the name of the current branch.
+	Resolutions struct {
+		// Pattern matching ticket IDs within the branch name. The
+		// ID is taken from a group named "id", the first group or
+		// the entire match, in that order.
+		Pattern string `toml:"pattern"`
+		// URL template, with access to the ID, of each resolution.
+		// If empty, the ID itself is used.
+		URL string `toml:"url"`
+	}
+
+	// History of the repository used as examples
+	// of its commit message style.
+	History struct {
+		// Count of recent commit messages to include.
+		Count int `toml:"count"`
+		// Paths limiting the commits to those that touched them.
+		Paths []string `toml:"paths"`
+		// Authors limiting the commits to those written by them.
+		Authors []string `toml:"authors"`
+	}
+
+	// Format is a user-defined commit format.
+	Format struct {
+		// Rules provided inline.
+		Rules string `toml:"rules"`
+		// File containing the rules, relative to the config file.
+		File           string       `toml:"file"`
+		TitleMaxLength int          `toml:"title_max_length"`
+		Body           llm.BodyRule `toml:"body"`
+		Trailers       []string     `toml:"trailers"`
 	}
 
 	Context struct {
 		File string `toml:"file"`
 	}
+
+	// Redact secrets from changes before they are sent.
+	Redact struct {
+		Mode redact.Mode `toml:"mode"`
+		// Patterns of additional secrets.
+		Patterns []string `toml:"patterns"`
+		// Ignore files matching these globs.
+		Ignore []string `toml:"ignore"`
+	}
+
+	// Cache of LLM responses.
+	Cache struct {
+		// Enabled unless explicitly disabled.
+		Enabled *bool `toml:"enabled"`
+		// MaxAge of a cached response, as a Go duration (e.g. "168h").
+		MaxAge string `toml:"max_age"`
+		// MaxSize of all cached responses, in megabytes.
+		MaxSize int `toml:"max_size"`
+	}
+
+	// Release of new versions.
+	Release struct {
+		// Bump command, run by the shell before tagging a release
+		// with the new version in $GITDO_VERSION (e.g. "1.2.3") and
+		// its tag in $GITDO_TAG. Any changes it makes are committed.
+		Bump string `toml:"bump"`
+	}
+
+	Team map[string]*TeamMember
+
+	TeamMember struct {
+		Name  string `toml:"name"`
+		Email string `toml:"email"`
+	}
+
+	// Tracker of the issues referenced by commits.
+	Tracker struct {
+		Kind tracker.Kind `toml:"kind"`
+		// URL of the tracker's API. For the JSON tracker this
+		// is a template of the issue's URL, with access to the ID.
+		URL string `toml:"url"`
+		// Project ("owner/repo" or project path) that issues belong to.
+		Project          string `toml:"project"`
+		TitleField       string `toml:"title_field"`
+		DescriptionField string `toml:"description_field"`
+	}
+)
+
+const (
+	defaultHistoryCount = 10
 )
 
 var (
 	ErrNoConfig       = errors.New("no config file found")
 	ErrNoContext      = errors.New("no context file available")
 	ErrInvalidVersion = errors.New("unknown version specified")
+	ErrInvalidBudget  = errors.New("unknown budget strategy specified")
+	ErrUnknownFormat  = errors.New("unknown commit format specified")
+	ErrInvalidFormat  = errors.New("invalid commit format definition")
+	ErrInvalidPattern = errors.New("invalid resolutions pattern or url")
+	ErrInvalidTracker = errors.New("invalid issue tracker definition")
+	ErrInvalidTeam    = errors.New("invalid team member definition")
+	ErrInvalidRedact  = errors.New("invalid redact mode or pattern")
+	ErrInvalidCache   = errors.New("invalid cache max age or size")
+	ErrInvalidScopes  = errors.New("invalid scope path or unknown scope")
 
 	configFileAliases = [...]string{
 		".do.toml",
@@ -73,6 +206,10 @@ func LoadFrom(fs fs.FS) (*Config, error) {
 
 			dst.configFs = fs
 
+			if err := dst.validate(); err != nil {
+				return nil, err
+			}
+
 			return &dst, nil
 		}
 	}
@@ -80,6 +217,29 @@ func LoadFrom(fs fs.FS) (*Config, error) {
 	return nil, ErrNoConfig
 }
 
+// LoadTeamFrom the personal team roster, located
+// at .gitdo/team.toml within the fs.
+func LoadTeamFrom(fs fs.FS) (Team, error) {
+	f, err := fs.Open(
+		filepath.Join(".gitdo", "team.toml"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dst Team
+	if _, err := toml.NewDecoder(f).Decode(&dst); err != nil {
+		return nil, err
+	}
+
+	if err := dst.validate(); err != nil {
+		return nil, err
+	}
+
+	return dst, nil
+}
+
 func Exists(f fs.FS) (bool, string) {
 	for _, variant := range configFileAliases {
 		if _, err := fs.Stat(f, variant); err == nil {
@@ -114,6 +274,381 @@ func WriteDefault(dir string) error {
 	)
 }
 
+// TokensFor the provided model. A model-specific budget
+// takes precedence over the general budget.
+func (recv *Budget) TokensFor(model string) int {
+	if t, f := recv.Models[model]; f {
+		return t
+	}
+
+	return recv.Tokens
+}
+
+// Limit of commit messages to include. If no count
+// is configured, a default of 10 is used.
+func (recv *History) Limit() int {
+	if recv.Count > 0 {
+		return recv.Count
+	}
+
+	return defaultHistoryCount
+}
+
+// FromBranch extracts the resolutions referenced by the branch name.
+func (recv *Resolutions) FromBranch(branch string) ([]string, error) {
+	pattern, urlTmpl, err := recv.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	group := 0
+	if i := pattern.SubexpIndex("id"); i > 0 {
+		group = i
+	} else if pattern.NumSubexp() > 0 {
+		group = 1
+	}
+
+	var returner []string
+	for _, m := range pattern.FindAllStringSubmatch(branch, -1) {
+		id := m[group]
+		if len(id) == 0 {
+			continue
+		}
+
+		resolution := id
+		if urlTmpl != nil {
+			var buf strings.Builder
+			if err := urlTmpl.Execute(&buf, struct {
+				ID string
+			}{
+				ID: id,
+			}); err != nil {
+				return nil, errors.Join(ErrInvalidPattern, err)
+			}
+
+			resolution = buf.String()
+		}
+
+		if !slices.Contains(returner, resolution) {
+			returner = append(returner, resolution)
+		}
+	}
+
+	return returner, nil
+}
+
+func (recv *Resolutions) compile() (*regexp.Regexp, *template.Template, error) {
+	pattern, err := regexp.Compile(recv.Pattern)
+	if err != nil || len(recv.Pattern) == 0 {
+		return nil, nil, errors.Join(ErrInvalidPattern, err)
+	}
+
+	if len(recv.URL) == 0 {
+		return pattern, nil, nil
+	}
+
+	urlTmpl, err := template.New("url").
+		Option("missingkey=error").
+		Parse(recv.URL)
+	if err != nil {
+		return nil, nil, errors.Join(ErrInvalidPattern, err)
+	}
+
+	return pattern, urlTmpl, nil
+}
+
+// CustomFormat of the configured commit format.
+//
+// If a built-in format is being used, nil is returned.
+func (recv *Config) CustomFormat() (*llm.FormatSpec, error) {
+	if recv.Commit == nil {
+		return nil, nil
+	}
+
+	f, found := recv.Commit.Formats[string(recv.Commit.Format)]
+	if !found {
+		return nil, nil
+	}
+
+	spec := &llm.FormatSpec{
+		Name:           string(recv.Commit.Format),
+		Rules:          f.Rules,
+		TitleMaxLength: f.TitleMaxLength,
+		Body:           f.Body,
+		Trailers:       f.Trailers,
+	}
+
+	if len(f.File) > 0 {
+		rules, err := fs.ReadFile(recv.configFs, f.File)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidFormat, err)
+		}
+
+		spec.Rules = string(rules)
+	}
+
+	return spec, nil
+}
+
+// Redactor of secrets. If not configured,
+// secrets are redacted by the built-in detectors.
+func (recv *Config) Redactor(opts ...redact.Opt) (*redact.Redactor, error) {
+	if recv.Redact != nil {
+		opts = append(opts,
+			redact.WithMode(recv.Redact.Mode),
+			redact.WithPatterns(recv.Redact.Patterns...),
+			redact.WithIgnore(recv.Redact.Ignore...),
+		)
+	}
+
+	r, err := redact.New(opts...)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidRedact, err)
+	}
+
+	return r, nil
+}
+
+// For the changed files, the scope that they all map to. If they
+// map to several scopes, or some to none, the scope is empty.
+//
+// False is returned if no paths are mapped, as no scope
+// can be determined.
+func (recv *Scopes) For(files []string) (string, bool) {
+	if len(recv.Paths) == 0 {
+		return "", false
+	}
+
+	// the most specific (i.e. longest) glob takes precedence
+	globs := slices.Collect(maps.Keys(recv.Paths))
+	slices.SortFunc(globs, func(a, b string) int {
+		if c := cmp.Compare(len(b), len(a)); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	var scope string
+	for i, f := range files {
+		var fileScope string
+		for _, g := range globs {
+			if matchGlob(g, f) {
+				fileScope = recv.Paths[g]
+
+				break
+			}
+		}
+
+		if i > 0 && fileScope != scope {
+			return "", true
+		}
+
+		scope = fileScope
+	}
+
+	return scope, true
+}
+
+func (recv *Scopes) validate() error {
+	for g, scope := range recv.Paths {
+		if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+			return errors.Join(ErrInvalidScopes, err)
+		}
+
+		if len(scope) == 0 ||
+			(len(recv.Allowed) > 0 && !slices.Contains(recv.Allowed, scope)) {
+			return ErrInvalidScopes
+		}
+	}
+
+	return nil
+}
+
+// matchGlob matches the file against the glob, where "**"
+// matches zero or more directories. A glob ending in "/"
+// matches everything within the directory.
+func matchGlob(glob, file string) bool {
+	if strings.HasSuffix(glob, "/") {
+		glob += "**"
+	}
+
+	return matchSegments(
+		strings.Split(glob, "/"),
+		strings.Split(file, "/"),
+	)
+}
+
+func matchSegments(glob, file []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := range len(file) + 1 {
+				if matchSegments(glob[1:], file[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(file) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(glob[0], file[0]); !ok {
+			return false
+		}
+
+		glob, file = glob[1:], file[1:]
+	}
+
+	return len(file) == 0
+}
+
+// ResponseCache stored in dir. If not configured, the cache's
+// default limits are used. Nil is returned if it is disabled.
+func (recv *Config) ResponseCache(dir string) (*cache.Cache, error) {
+	var opts []cache.Opt
+	if recv.Cache != nil {
+		if recv.Cache.Enabled != nil && !*recv.Cache.Enabled {
+			return nil, nil
+		}
+
+		if err := recv.Cache.validate(); err != nil {
+			return nil, err
+		}
+
+		if age, _ := recv.Cache.maxAge(); age > 0 {
+			opts = append(opts, cache.WithMaxAge(age))
+		}
+
+		if recv.Cache.MaxSize > 0 {
+			opts = append(opts, cache.WithMaxSize(int64(recv.Cache.MaxSize)<<20))
+		}
+	}
+
+	return cache.New(dir, opts...)
+}
+
+// maxAge of a cached response, or zero if it is not configured.
+func (recv *Cache) maxAge() (time.Duration, error) {
+	if len(recv.MaxAge) == 0 {
+		return 0, nil
+	}
+
+	age, err := time.ParseDuration(recv.MaxAge)
+	if err != nil || age < 0 {
+		return 0, ErrInvalidCache
+	}
+
+	return age, nil
+}
+
+func (recv *Cache) validate() error {
+	if _, err := recv.maxAge(); err != nil {
+		return err
+	}
+
+	if recv.MaxSize < 0 {
+		return ErrInvalidCache
+	}
+
+	return nil
+}
+
+// CoAuthor identity of the member, as used
+// by the Co-authored-by trailer.
+func (recv *TeamMember) CoAuthor() string {
+	return fmt.Sprintf("%s <%s>", recv.Name, recv.Email)
+}
+
+func (recv Team) validate() error {
+	for _, m := range recv {
+		if m == nil || len(m.Name) == 0 || len(m.Email) == 0 {
+			return ErrInvalidTeam
+		}
+	}
+
+	return nil
+}
+
+// Domain of the tracker, used to look up its credentials.
+func (recv *Tracker) Domain() string {
+	base := recv.URL
+	if len(base) == 0 {
+		base = recv.Kind.DefaultBaseURL()
+	}
+
+	u, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+func (recv *Config) validate() error {
+	if recv.LLM != nil && recv.LLM.Budget != nil {
+		if s := recv.LLM.Budget.Strategy; len(s) > 0 && !s.Valid() {
+			return ErrInvalidBudget
+		}
+	}
+
+	if err := recv.Team.validate(); err != nil {
+		return err
+	}
+
+	if _, err := recv.Redactor(); err != nil {
+		return err
+	}
+
+	if recv.Cache != nil {
+		if err := recv.Cache.validate(); err != nil {
+			return err
+		}
+	}
+
+	if recv.Tracker != nil {
+		if !recv.Tracker.Kind.Valid() || len(recv.Tracker.Domain()) == 0 {
+			return ErrInvalidTracker
+		}
+	}
+
+	if recv.Commit != nil {
+		for _, f := range recv.Commit.Formats {
+			if f == nil || !f.Body.Valid() {
+				return ErrInvalidFormat
+			}
+		}
+
+		switch recv.Commit.Format {
+		case "", git.CommitFormatGithub, git.CommitFormatConventional:
+		default:
+			if _, found := recv.Commit.Formats[string(recv.Commit.Format)]; !found {
+				return ErrUnknownFormat
+			}
+		}
+
+		if _, err := recv.CustomFormat(); err != nil {
+			return err
+		}
+
+		if recv.Commit.Resolutions != nil {
+			if _, _, err := recv.Commit.Resolutions.compile(); err != nil {
+				return err
+			}
+		}
+
+		if recv.Commit.Scopes != nil {
+			if err := recv.Commit.Scopes.validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (recv *Config) LoadContextFile() (io.ReadCloser, error) {
 	if recv.LLM == nil {
 		return nil, ErrNoContext
@@ -8,10 +8,12 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/julianwyz/git-do/internal/config"
+	"github.com/julianwyz/git-do/internal/llm"
 )
 
 var (
@@ -105,6 +107,379 @@ func TestLoadFrom(t *testing.T) {
 	})
 }
 
+func TestBudget(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "budget"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.LLM.Budget.TokensFor("small-model") != 500 {
+		t.Fatal("expected model specific budget")
+	}
+
+	if c.LLM.Budget.TokensFor("other-model") != 1000 {
+		t.Fatal("expected default budget")
+	}
+
+	t.Run("bad strategy", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "bad_budget"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = config.LoadFrom(sub)
+		if !errors.Is(err, config.ErrInvalidBudget) {
+			t.Fatal("expected invalid budget error")
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "history"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := c.Commit.History
+	if h.Limit() != 10 {
+		t.Fatal("expected default history count")
+	}
+
+	if len(h.Paths) != 1 || len(h.Authors) != 1 {
+		t.Fatal("expected history filters")
+	}
+}
+
+func TestResolutions(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "resolutions"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolutions, err := c.Commit.Resolutions.FromBranch("feature/PROJ-1234-PROJ-99-thing-PROJ-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(resolutions, []string{
+		"https://jira.example.com/browse/PROJ-1234",
+		"https://jira.example.com/browse/PROJ-99",
+	}) {
+		t.Fatalf("unexpected resolutions: %q", resolutions)
+	}
+
+	t.Run("first group", func(t *testing.T) {
+		r := &config.Resolutions{
+			Pattern: `issue-(\d+)`,
+		}
+
+		resolutions, err := r.FromBranch("fix/issue-42")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(resolutions, []string{"42"}) {
+			t.Fatalf("unexpected resolutions: %q", resolutions)
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		resolutions, err := c.Commit.Resolutions.FromBranch("main")
+		if err != nil || len(resolutions) > 0 {
+			t.Fatalf("expected no resolutions, got %q: %v", resolutions, err)
+		}
+	})
+
+	t.Run("bad pattern", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "bad_resolutions"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = config.LoadFrom(sub)
+		if !errors.Is(err, config.ErrInvalidPattern) {
+			t.Fatal("expected invalid pattern error")
+		}
+	})
+}
+
+func TestTracker(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "tracker"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := c.Tracker.Domain(); d != "tickets.example.com" {
+		t.Fatalf("unexpected tracker domain %q", d)
+	}
+
+	if d := (&config.Tracker{Kind: "github"}).Domain(); d != "api.github.com" {
+		t.Fatalf("expected default github domain, got %q", d)
+	}
+
+	t.Run("bad kind", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "bad_tracker"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = config.LoadFrom(sub)
+		if !errors.Is(err, config.ErrInvalidTracker) {
+			t.Fatal("expected invalid tracker error")
+		}
+	})
+}
+
+func TestTeam(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "team"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a := c.Team["ada"].CoAuthor(); a != "Ada Lovelace <ada@example.com>" {
+		t.Fatalf("unexpected co-author %q", a)
+	}
+
+	personal, err := config.LoadTeamFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a := personal["grace"].CoAuthor(); a != "Grace Hopper <grace@example.com>" {
+		t.Fatalf("unexpected co-author %q", a)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "bad_redact"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = config.LoadFrom(sub)
+	if !errors.Is(err, config.ErrInvalidRedact) {
+		t.Fatal("expected invalid redact error")
+	}
+}
+
+func TestScopes(t *testing.T) {
+	t.Run("mapped", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "scopes"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := config.LoadFrom(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			files    []string
+			expected string
+		}{
+			{[]string{"internal/cli/commit.go", "cmd/git-do/main.go"}, "cli"},
+			{[]string{"internal/git/git.go", "internal/llm/llm.go"}, "git"},
+			{[]string{"README.md"}, "docs"},
+			// several scopes
+			{[]string{"README.md", "internal/cli/commit.go"}, ""},
+			// unmapped files
+			{[]string{"go.mod", "internal/cli/commit.go"}, ""},
+		} {
+			scope, found := c.Commit.Scopes.For(tc.files)
+			if !found {
+				t.Fatal("expected a scope to be determined")
+			}
+
+			if scope != tc.expected {
+				t.Fatalf("expected scope %q for %v, got %q", tc.expected, tc.files, scope)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "bad_scopes"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = config.LoadFrom(sub)
+		if !errors.Is(err, config.ErrInvalidScopes) {
+			t.Fatal("expected invalid scopes error")
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "cache"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := config.LoadFrom(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rc, err := c.ResponseCache(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rc != nil {
+			t.Fatal("expected no cache")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "bad_cache"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = config.LoadFrom(sub)
+		if !errors.Is(err, config.ErrInvalidCache) {
+			t.Fatal("expected invalid cache error")
+		}
+	})
+}
+
+func TestCustomFormat(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "custom_format"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := c.CustomFormat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec == nil || spec.Name != "kernel" {
+		t.Fatal("expected kernel format")
+	}
+
+	if !strings.Contains(spec.Rules, "<subsystem>: <short summary>") {
+		t.Fatal("expected rules to be loaded from file")
+	}
+
+	if spec.TitleMaxLength != 72 || spec.Body != llm.BodyRuleRequired {
+		t.Fatal("unexpected format rules")
+	}
+
+	t.Run("built-in", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "1"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := config.LoadFrom(sub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		spec, err := c.CustomFormat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if spec != nil {
+			t.Fatal("expected no custom format")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		sub, err := fs.Sub(
+			fixtures,
+			filepath.Join("fixtures", "unknown_format"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = config.LoadFrom(sub)
+		if !errors.Is(err, config.ErrUnknownFormat) {
+			t.Fatal("expected unknown format error")
+		}
+	})
+}
+
 func TestExists(t *testing.T) {
 	variants := [][]string{
 		{"1", ".do.toml"},
@@ -205,3 +580,22 @@ func TestLoadContextFile(t *testing.T) {
 		t.Fatal("bad content")
 	}
 }
+
+func TestRelease(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "release"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := config.LoadFrom(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Release == nil || !strings.Contains(c.Release.Bump, "$GITDO_VERSION") {
+		t.Fatal("expected a bump command")
+	}
+}
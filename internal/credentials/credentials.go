@@ -13,6 +13,12 @@ type (
 	Credentials struct {
 		APIKey string
 	}
+
+	// TrackerCredentials authenticate with an issue tracker.
+	TrackerCredentials struct {
+		Username string
+		Token    string
+	}
 )
 
 var (
@@ -20,7 +26,9 @@ var (
 )
 
 const (
-	apiKeyFieldName = "api_key"
+	apiKeyFieldName   = "api_key"
+	usernameFieldName = "username"
+	tokenFieldName    = "token"
 )
 
 func LoadFrom(fs fs.FS, domain string) (*Credentials, error) {
@@ -47,6 +55,36 @@ func LoadFrom(fs fs.FS, domain string) (*Credentials, error) {
 	return returner, nil
 }
 
+// LoadTrackerFrom the section of the credentials file named after the
+// issue tracker's domain.
+//
+// Unlike API keys, the default section is never used. This prevents the
+// default key from being sent to a tracker it was not intended for.
+func LoadTrackerFrom(fs fs.FS, domain string) (*TrackerCredentials, error) {
+	f, err := fs.Open(
+		filepath.Join(".gitdo", "credentials"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cfg, err := ini.Load(f)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := cfg.GetSection(domain)
+	if err != nil || !s.HasKey(tokenFieldName) {
+		return nil, ErrDomain
+	}
+
+	return &TrackerCredentials{
+		Username: s.Key(usernameFieldName).String(),
+		Token:    s.Key(tokenFieldName).String(),
+	}, nil
+}
+
 func WriteDefault(dir, key string) (string, error) {
 	cfg := ini.Empty()
 	s, err := cfg.NewSection("default")
@@ -145,6 +145,32 @@ func TestLoadFrom(t *testing.T) {
 	})
 }
 
+func TestLoadTrackerFrom(t *testing.T) {
+	sub, err := fs.Sub(
+		fixtures,
+		filepath.Join("fixtures", "ok"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := credentials.LoadTrackerFrom(sub, "jira.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if creds.Username != "jane@example.com" || creds.Token != "jira token" {
+		t.Fatal("unexpected credential value")
+	}
+
+	t.Run("no default", func(t *testing.T) {
+		_, err := credentials.LoadTrackerFrom(sub, "api.github.com")
+		if !errors.Is(err, credentials.ErrDomain) {
+			t.Fatal("expected the default section to be ignored")
+		}
+	})
+}
+
 func TestExists(t *testing.T) {
 	sub, err := fs.Sub(
 		fixtures,
@@ -0,0 +1,248 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// binaryFile metadata, describing a binary or
+	// LFS file in place of its patch.
+	binaryFile struct {
+		path    string
+		mime    string
+		lfs     bool
+		oldSize int64
+		newSize int64
+		// oldLFSSize and newLFSSize of the LFS objects
+		oldLFSSize int64
+		newLFSSize int64
+	}
+)
+
+const (
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	binaryPatchMarker = "GIT binary patch"
+	// sizeUnknown of a file that does not exist on one side of the patch
+	sizeUnknown = -1
+)
+
+var (
+	indexLinePattern = regexp.MustCompile(`^index ([0-9a-f]+)\.\.([0-9a-f]+)`)
+	lfsSizeLine      = regexp.MustCompile(`^([ +-])size (\d+)$`)
+	binaryDiffLine   = regexp.MustCompile(`^Binary files .* differ$`)
+)
+
+// describeBinaries replaces the changes of each binary or LFS
+// file within the patch with a description of the file.
+//
+// Patches of other files are unchanged.
+func describeBinaries(ctx context.Context, wd, patch string) string {
+	if !strings.Contains(patch, "Binary files ") &&
+		!strings.Contains(patch, binaryPatchMarker) &&
+		!strings.Contains(patch, lfsPointerVersion) {
+		// nothing to do, the most common case
+		return patch
+	}
+
+	var sb strings.Builder
+	for _, section := range splitFilePatches(patch) {
+		if strings.HasPrefix(section, filePatchPrefix) {
+			section = describeBinary(ctx, wd, section)
+		}
+
+		sb.WriteString(section)
+	}
+
+	return sb.String()
+}
+
+// splitFilePatches into the patch of each file. Anything preceding
+// the first file's patch (e.g. a commit message) is its own section.
+func splitFilePatches(patch string) []string {
+	var (
+		sections []string
+		start    int
+	)
+
+	for {
+		i := strings.Index(patch[start:], "\n"+filePatchPrefix)
+		if i < 0 {
+			break
+		}
+
+		end := start + i + 1
+		sections = append(sections, patch[start:end])
+		start = end
+	}
+
+	return append(sections, patch[start:])
+}
+
+func describeBinary(ctx context.Context, wd, patch string) string {
+	lines := strings.Split(patch, "\n")
+
+	var (
+		header []string
+		file   = binaryFile{
+			path:       patchFileName(patch),
+			oldSize:    sizeUnknown,
+			newSize:    sizeUnknown,
+			oldLFSSize: sizeUnknown,
+			newLFSSize: sizeUnknown,
+		}
+		isBinary         bool
+		inHunk           bool
+		oldHash, newHash string
+	)
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case binaryDiffLine.MatchString(line),
+			line == binaryPatchMarker:
+			isBinary = true
+		case inHunk && len(line) > 0 && line[1:] == lfsPointerVersion:
+			file.lfs = true
+		}
+
+		if m := lfsSizeLine.FindStringSubmatch(line); m != nil && inHunk {
+			size, _ := strconv.ParseInt(m[2], 10, 64)
+			if m[1] != "+" {
+				file.oldLFSSize = size
+			}
+			if m[1] != "-" {
+				file.newLFSSize = size
+			}
+		}
+
+		if m := indexLinePattern.FindStringSubmatch(line); m != nil {
+			oldHash, newHash = m[1], m[2]
+		}
+
+		if !inHunk && !isBinary {
+			header = append(header, line)
+		}
+	}
+
+	if !isBinary && !file.lfs {
+		return patch
+	}
+
+	file.oldSize = blobSize(ctx, wd, oldHash)
+	file.newSize = blobSize(ctx, wd, newHash)
+	if file.newSize == sizeUnknown && !isNullHash(newHash) {
+		// the working tree version, which is not yet a blob
+		if fi, err := os.Stat(worktreeFile(ctx, wd, file.path)); err == nil {
+			file.newSize = fi.Size()
+		}
+	}
+
+	file.mime = guessMIME(worktreeFile(ctx, wd, file.path))
+
+	return strings.Join(header, "\n") + "\n" + file.String()
+}
+
+func (recv binaryFile) String() string {
+	var sb strings.Builder
+
+	if recv.lfs {
+		sb.WriteString("GIT LFS FILE (pointer omitted)\n")
+	} else {
+		sb.WriteString("BINARY FILE (contents omitted)\n")
+	}
+
+	fmt.Fprintf(&sb, "path: %s\n", recv.path)
+	fmt.Fprintf(&sb, "type: %s\n", recv.mime)
+
+	if recv.lfs {
+		fmt.Fprintf(&sb, "old object size: %s\n", formatSize(recv.oldLFSSize))
+		fmt.Fprintf(&sb, "new object size: %s\n", formatSize(recv.newLFSSize))
+	} else {
+		fmt.Fprintf(&sb, "old size: %s\n", formatSize(recv.oldSize))
+		fmt.Fprintf(&sb, "new size: %s\n", formatSize(recv.newSize))
+	}
+
+	return sb.String()
+}
+
+func formatSize(size int64) string {
+	if size == sizeUnknown {
+		return "none"
+	}
+
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// blobSize of the object identified by hash, if it exists.
+func blobSize(ctx context.Context, wd, hash string) int64 {
+	if len(hash) == 0 || isNullHash(hash) {
+		return sizeUnknown
+	}
+
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		nil,
+		"cat-file",
+		"-s",
+		hash,
+	).Run(); err != nil {
+		return sizeUnknown
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(dst.String()), 10, 64)
+	if err != nil {
+		return sizeUnknown
+	}
+
+	return size
+}
+
+func isNullHash(hash string) bool {
+	return len(strings.Trim(hash, "0")) == 0
+}
+
+// worktreeFile resolves the path of a patched file, which is
+// relative to either the repo's root or wd (e.g. untracked files).
+func worktreeFile(ctx context.Context, wd, name string) string {
+	if root, err := topLevel(ctx, wd); err == nil {
+		root = filepath.Join(root, name)
+		if _, err := os.Stat(root); err == nil {
+			return root
+		}
+	}
+
+	return filepath.Join(wd, name)
+}
+
+// guessMIME type of the file from its extension or,
+// failing that, its content in the working tree.
+func guessMIME(file string) string {
+	if t := mime.TypeByExtension(path.Ext(file)); len(t) > 0 {
+		return t
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+
+	return http.DetectContentType(buf[:n])
+}
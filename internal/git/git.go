@@ -26,6 +26,10 @@ const (
 	CommitFormatConventional = CommitFormat("conventional")
 )
 
+var (
+	ErrUnknownRef = errors.New("git: unknown commit reference")
+)
+
 // Init a git repo.
 func Init(ctx context.Context, wd string, out io.Writer) error {
 	return prepareGitCmd(
@@ -137,7 +141,7 @@ func Status(
 				err = nil
 			}
 
-			if !yield(buf.String(), err) {
+			if !yield(describeBinaries(ctx, wd, buf.String()), err) {
 				return
 			}
 		}
@@ -168,91 +172,91 @@ func HeadHash(ctx context.Context, wd string) (string, error) {
 	return strings.TrimSpace(buf.String()), nil
 }
 
-// CommitsBetween the provided refRange
-//
-// This provides an iterator to step through the commits
-// in the range.
-//
-// refRange is expected to have...
-//
-//	[0] = start
-//	[1] = end
-//
-// If a single ref is provided, that will be the only commit
-// returned by the iterator.
-func CommitsBetween(
-	ctx context.Context,
-	wd string,
-	refRange []string,
-) (iter.Seq2[string, error], error) {
-	if len(refRange) == 1 {
-		refRange = append(refRange, refRange[0])
-	}
-
-	ref := strings.Join(refRange, "..")
+// CommitHashes reported by `git log` given the args, which may be
+// anything it accepts (e.g. "A..B", "--author=<pattern>" or "-- <paths>").
+func CommitHashes(ctx context.Context, wd string, args ...string) ([]string, error) {
 	commitBatch := &bytes.Buffer{}
 	if err := prepareGitCmd(
 		ctx,
 		wd,
 		commitBatch,
 		os.Stderr,
-		"log",
-		"--pretty=format:%H",
-		ref,
+		slices.Concat([]string{"log", "--pretty=format:%H"}, args)...,
 	).Run(); err != nil {
 		return nil, err
 	}
 
+	var hashes []string
 	scanner := bufio.NewScanner(commitBatch)
+	for scanner.Scan() {
+		if hash := strings.TrimSpace(scanner.Text()); len(hash) > 0 {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes, scanner.Err()
+}
+
+// ListCommits provides an iterator to step through each commit
+// reported by `git log` given the args. See CommitHashes.
+//
+// When the args are limited to paths, so are the commits' changes.
+func ListCommits(
+	ctx context.Context,
+	wd string,
+	args ...string,
+) (iter.Seq2[string, error], error) {
+	hashes, err := CommitHashes(ctx, wd, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		showArgs []string
+		options  = args
+	)
+	if i := slices.Index(args, "--"); i >= 0 {
+		options = args[:i]
+		showArgs = append(showArgs, args[i:]...)
+	}
+	if slices.Contains(options, "--first-parent") {
+		// merges are shown as the changes they brought in
+		showArgs = append([]string{"--first-parent"}, showArgs...)
+	}
 
 	return func(yield func(string, error) bool) {
-		doHash := func(hash string) bool {
+		for _, hash := range hashes {
 			log.Debug().
 				Str("hash", hash).
 				Msg("include commit in summary")
 
 			buf := &bytes.Buffer{}
-			err := ShowCommit(ctx, wd, hash, buf)
+			err := ShowCommit(ctx, wd, hash, buf, showArgs...)
 
-			return yield(buf.String(), err)
-		}
-
-		for scanner.Scan() {
-			if !doHash(scanner.Text()) {
+			if !yield(describeBinaries(ctx, wd, buf.String()), err) {
 				return
 			}
 		}
-
-		if err := scanner.Err(); err != nil {
-			yield("", err)
-
-			return
-		}
-
-		// do our base commit as it won't be included
-		// in the list
-		if !doHash(refRange[0]) {
-			return
-		}
 	}, nil
 }
 
 // ShowCommit identified by ref at the git repo at wd
 //
-// stdout will be piped to the dst.
+// stdout will be piped to the dst. Any args are
+// passed to `git show` (e.g. "-- <paths>").
 func ShowCommit(
 	ctx context.Context,
 	wd,
 	ref string,
 	dst io.Writer,
+	args ...string,
 ) error {
 	return prepareGitCmd(
 		ctx,
 		wd,
 		dst,
 		os.Stderr,
-		"show",
-		ref,
+		slices.Concat([]string{"show", ref}, args)...,
 	).Run()
 }
 
@@ -271,7 +275,8 @@ func DiffsOfCommit(
 	}
 
 	rc, _ := RootCommit(ctx, wd)
-	if rc == ref {
+	hash, _ := RevParse(ctx, wd, ref)
+	if slices.Contains(strings.Fields(rc), hash) {
 		dn, err := hashDevNull(ctx, wd)
 		if err != nil {
 			return err
@@ -362,6 +367,7 @@ func ListCommitChanges(ctx context.Context, wd, ref string) (iter.Seq2[string, e
 		"diff-tree",
 		"--no-commit-id",
 		"--name-only",
+		"--root",
 		"-r",
 		ref,
 	).Run(); err != nil {
@@ -382,7 +388,7 @@ func ListCommitChanges(ctx context.Context, wd, ref string) (iter.Seq2[string, e
 				diffs,
 			)
 
-			if !yield(diffs.String(), err) {
+			if !yield(describeBinaries(ctx, wd, diffs.String()), err) {
 				return
 			}
 		}
@@ -413,13 +419,65 @@ func ListStaged(ctx context.Context, wd string) (iter.Seq2[string, error], error
 			diffs := &bytes.Buffer{}
 			err := StagedDiffs(ctx, wd, fileName, diffs)
 
-			if !yield(diffs.String(), err) {
+			if !yield(describeBinaries(ctx, wd, diffs.String()), err) {
 				return
 			}
 		}
 	}, nil
 }
 
+// ListDiff provides an iterator to step through the changes to
+// each file reported by `git diff` given the args, which may be
+// anything it accepts (e.g. "--cached", "A B" or "-- <paths>").
+func ListDiff(ctx context.Context, wd string, args ...string) (iter.Seq2[string, error], error) {
+	files, err := changedFiles(ctx, wd, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	options := args
+	if i := slices.Index(args, "--"); i >= 0 {
+		options = args[:i]
+	}
+
+	return func(yield func(string, error) bool) {
+		for _, f := range files {
+			diffs := &bytes.Buffer{}
+			err := prepareGitCmd(
+				ctx,
+				wd,
+				diffs,
+				os.Stderr,
+				slices.Concat(
+					[]string{"diff", "--unified=12", "--raw"},
+					options,
+					[]string{"--", f},
+				)...,
+			).Run()
+
+			if !yield(describeBinaries(ctx, wd, diffs.String()), err) {
+				return
+			}
+		}
+	}, nil
+}
+
+// ListWorktree provides an iterator to step through the changes
+// to each tracked file in the working tree, staged or not, since
+// HEAD. Only the paths' changes are included, if provided.
+func ListWorktree(ctx context.Context, wd string, paths ...string) (iter.Seq2[string, error], error) {
+	base, err := HeadHash(ctx, wd)
+	if err != nil {
+		// nothing has been committed yet
+		base, err = hashDevNull(ctx, wd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ListDiff(ctx, wd, slices.Concat([]string{base, "--"}, paths)...)
+}
+
 // Commit the changes to the local git repo.
 func Commit(
 	ctx context.Context,
@@ -427,16 +485,16 @@ func Commit(
 	msg io.Reader,
 	args ...string,
 ) error {
-	cmdLine := slices.Concat(
-		[]string{"commit"},
-		args,
-	)
+	cmdLine := []string{"commit"}
 
 	if msg != nil {
-		// take from stdin
+		// take from stdin. This must precede the args,
+		// which may end with pathspecs
 		cmdLine = append(cmdLine, "-F", "-")
 	}
 
+	cmdLine = append(cmdLine, args...)
+
 	cmd := prepareGitCmd(
 		ctx,
 		wd,
@@ -450,6 +508,446 @@ func Commit(
 	return cmd.Run()
 }
 
+// ResetHard the current branch, index and working tree of the
+// git repo at wd to ref, discarding any changes since it.
+func ResetHard(ctx context.Context, wd, ref string) error {
+	return prepareGitCmd(
+		ctx,
+		wd,
+		nil,
+		os.Stderr,
+		"reset",
+		"--hard",
+		"--quiet",
+		ref,
+	).Run()
+}
+
+// RevParse resolves ref to the full hash of the commit
+// it references in the git repo at wd.
+func RevParse(ctx context.Context, wd, ref string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"rev-parse",
+		"--verify",
+		"--quiet",
+		ref+"^{commit}",
+	).Run(); err != nil {
+		return "", errors.Join(ErrUnknownRef, err)
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// IsAncestor reports whether the ancestor ref is reachable
+// from the descendant ref in the git repo at wd.
+func IsAncestor(ctx context.Context, wd, ancestor, descendant string) (bool, error) {
+	err := prepareGitCmd(
+		ctx,
+		wd,
+		nil,
+		os.Stderr,
+		"merge-base",
+		"--is-ancestor",
+		ancestor,
+		descendant,
+	).Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return false, nil
+	}
+
+	return false, err
+}
+
+// MergeBase resolves the full hash of the best common
+// ancestor of the refs in the git repo at wd.
+func MergeBase(ctx context.Context, wd, a, b string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"merge-base",
+		a,
+		b,
+	).Run(); err != nil {
+		return "", errors.Join(ErrUnknownRef, err)
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// CurrentBranch of the git repo at wd.
+//
+// If HEAD is detached, an empty string is returned.
+func CurrentBranch(ctx context.Context, wd string) (string, error) {
+	var dst bytes.Buffer
+	err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		nil,
+		"symbolic-ref",
+		"--quiet",
+		"--short",
+		"HEAD",
+	).Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// Upstream of the current branch of the git repo at wd.
+//
+// If the branch does not track an upstream, an empty string is returned.
+func Upstream(ctx context.Context, wd string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		nil,
+		"rev-parse",
+		"--abbrev-ref",
+		"--symbolic-full-name",
+		"@{upstream}",
+	).Run(); err != nil {
+		// not having an upstream is an error as far
+		// as git is concerned, but not for us
+		return "", nil
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// Reword the commit at ref with a new message, preserving its
+// tree, parents and authorship. Any commits that follow ref on
+// the current branch are rebased on top of the reworded commit.
+func Reword(
+	ctx context.Context,
+	wd,
+	ref string,
+	msg io.Reader,
+) error {
+	ref, err := RevParse(ctx, wd, ref)
+	if err != nil {
+		return err
+	}
+
+	var info bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&info,
+		os.Stderr,
+		"log",
+		"-1",
+		"--format=%T%n%P%n%an%n%ae%n%aI",
+		ref,
+	).Run(); err != nil {
+		return err
+	}
+
+	// tree, parents, author name, author email, author date
+	fields := strings.Split(info.String(), "\n")
+	if len(fields) < 5 {
+		return ErrUnknownRef
+	}
+
+	args := []string{"commit-tree", fields[0]}
+	for _, p := range strings.Fields(fields[1]) {
+		args = append(args, "-p", p)
+	}
+	args = append(args, "-F", "-")
+
+	var newRef bytes.Buffer
+	cmd := prepareGitCmd(
+		ctx,
+		wd,
+		&newRef,
+		os.Stderr,
+		args...,
+	)
+	cmd.Stdin = msg
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+fields[2],
+		"GIT_AUTHOR_EMAIL="+fields[3],
+		"GIT_AUTHOR_DATE="+fields[4],
+	)
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	newHash := strings.TrimSpace(newRef.String())
+
+	head, err := HeadHash(ctx, wd)
+	if err != nil {
+		return err
+	}
+
+	if head == ref {
+		return prepareGitCmd(
+			ctx,
+			wd,
+			os.Stdout,
+			os.Stderr,
+			"update-ref",
+			"-m",
+			"git-do: reword",
+			"HEAD",
+			newHash,
+			ref,
+		).Run()
+	}
+
+	// the trees are unchanged, so replaying the
+	// descendants on top of the new commit can not conflict
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		os.Stdout,
+		os.Stderr,
+		"rebase",
+		"--quiet",
+		"--autostash",
+		"--rebase-merges",
+		"--onto",
+		newHash,
+		ref,
+	).Run(); err != nil {
+		_ = prepareGitCmd(
+			ctx,
+			wd,
+			nil,
+			nil,
+			"rebase",
+			"--abort",
+		).Run()
+
+		return err
+	}
+
+	return nil
+}
+
+// RecentMessages provides the messages of, at most, the count most
+// recent commits of the git repo at wd. Merge commits and messages
+// containing the excludeTrailer are skipped.
+//
+// If authors or paths are provided, only commits written by one of
+// the authors, or touching one of the paths, are included.
+func RecentMessages(
+	ctx context.Context,
+	wd string,
+	count int,
+	excludeTrailer string,
+	authors,
+	paths []string,
+) ([]string, error) {
+	args := []string{
+		"log",
+		"--no-merges",
+		"--format=%B%x00",
+		fmt.Sprintf("--max-count=%d", count),
+	}
+
+	if len(excludeTrailer) > 0 {
+		args = append(args,
+			"--invert-grep",
+			"--grep=^"+excludeTrailer+":",
+		)
+	}
+
+	for _, a := range authors {
+		args = append(args, "--author="+a)
+	}
+
+	args = append(args, "--")
+	args = append(args, paths...)
+
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		args...,
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	var msgs []string
+	for msg := range strings.SplitSeq(dst.String(), "\x00") {
+		if msg = strings.TrimSpace(msg); len(msg) > 0 {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return msgs, nil
+}
+
+// Editor resolves the editor git is configured to use
+// for the repo at wd.
+//
+// This follows git's own precedence of GIT_EDITOR, core.editor,
+// VISUAL and EDITOR.
+func Editor(ctx context.Context, wd string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"var",
+		"GIT_EDITOR",
+	).Run(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// Edit the provided content using the configured git editor
+// of the repo at wd.
+//
+// The edited content is returned once the editor exits.
+func Edit(ctx context.Context, wd, content string) (string, error) {
+	editor, err := Editor(ctx, wd)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp("", "GITDO_EDITMSG-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// git treats the editor as a shell snippet so that
+	// values such as "code --wait" work as expected
+	cmd := exec.CommandContext(
+		ctx,
+		"sh", "-c", editor+` "$@"`, editor, f.Name(),
+	)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = wd
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return string(edited), nil
+}
+
+// HooksDir of the git repo at wd.
+//
+// This respects the core.hooksPath configuration, and
+// the returned path is always absolute.
+func HooksDir(ctx context.Context, wd string) (string, error) {
+	return GitPath(ctx, wd, "hooks")
+}
+
+// GitPath resolves the absolute path of name within the
+// git directory of the repo at wd.
+func GitPath(ctx context.Context, wd, name string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"rev-parse",
+		"--path-format=absolute",
+		"--git-path",
+		name,
+	).Run(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// InterpretTrailers adds the trailers, each formatted as "Key: value",
+// to the trailer block of msg. Trailers that already exist with the
+// same value are not repeated.
+func InterpretTrailers(
+	ctx context.Context,
+	wd,
+	msg string,
+	trailers ...string,
+) (string, error) {
+	args := []string{
+		"interpret-trailers",
+		"--if-exists",
+		"addIfDifferent",
+	}
+	for _, t := range trailers {
+		args = append(args, "--trailer", t)
+	}
+
+	var dst bytes.Buffer
+	cmd := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		args...,
+	)
+	cmd.Stdin = strings.NewReader(msg)
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	return dst.String(), nil
+}
+
 func hashDevNull(ctx context.Context, wd string) (string, error) {
 	var dst bytes.Buffer
 	if err := prepareGitCmd(
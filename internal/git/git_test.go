@@ -3,9 +3,13 @@ package git_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"iter"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -607,6 +611,150 @@ func TestListStaged(t *testing.T) {
 	}
 }
 
+func TestListStaged__Binary(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{
+		// a PNG signature followed by NUL bytes
+		"image.png": append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 120)...),
+		"model.bin": []byte(`version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345
+`),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(
+			filepath.Join(wd, name),
+			content,
+			0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runGitCmd(
+		t.Context(),
+		wd,
+		"add",
+		".",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	seq, err := git.ListStaged(
+		t.Context(),
+		wd,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []string
+	for item, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) != 2 {
+		t.Fatalf("bad length: %d", len(items))
+	}
+
+	if !strings.Contains(
+		items[0],
+		"BINARY FILE (contents omitted)\npath: image.png\ntype: image/png\nold size: none\nnew size: 128 bytes\n",
+	) {
+		t.Fatalf("unexpected binary patch:\n%s", items[0])
+	}
+
+	for _, expected := range []string{
+		"GIT LFS FILE (pointer omitted)\npath: model.bin\n",
+		"old object size: none\nnew object size: 12345 bytes\n",
+	} {
+		if !strings.Contains(items[1], expected) {
+			t.Fatalf("unexpected LFS patch:\n%s", items[1])
+		}
+	}
+
+	if strings.Contains(items[1], "oid sha256:") {
+		t.Fatal("LFS pointer was not omitted")
+	}
+}
+
+func TestListWorktree(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"staged.txt":   "staged",
+		"unstaged.txt": "unstaged",
+	} {
+		if err := os.WriteFile(
+			filepath.Join(wd, name),
+			[]byte(content),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runGitCmd(
+		t.Context(),
+		wd,
+		"add",
+		"staged.txt",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	collect := func(seq iter.Seq2[string, error], err error) []string {
+		t.Helper()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var items []string
+		for item, err := range seq {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			items = append(items, item)
+		}
+
+		return items
+	}
+
+	// nothing has been committed yet, so untracked files are excluded
+	items := collect(git.ListWorktree(t.Context(), wd))
+	if len(items) != 1 || !strings.Contains(items[0], "+staged") {
+		t.Fatalf("unexpected changes: %v", items)
+	}
+
+	if err := runGitCmd(
+		t.Context(),
+		wd,
+		"add",
+		"unstaged.txt",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if items := collect(git.ListWorktree(t.Context(), wd, "unstaged.txt")); len(items) != 1 {
+		t.Fatalf("expected only the path's changes: %v", items)
+	}
+
+	if items := collect(git.ListDiff(t.Context(), wd, "--cached")); len(items) != 2 {
+		t.Fatalf("expected both staged changes: %v", items)
+	}
+}
+
 func TestCommit(t *testing.T) {
 	wd, err := initNewDir(t.Context())
 	if err != nil {
@@ -735,149 +883,500 @@ func TestListCommitChanges(t *testing.T) {
 	}
 }
 
-func TestCommitsBetween(t *testing.T) {
-	t.Run("single", func(t *testing.T) {
-		wd, err := initNewDir(t.Context())
-		if err != nil {
-			t.Fatal(err)
-		}
+func TestListCommitChanges__Root(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		if err := os.WriteFile(
-			filepath.Join(wd, "test.txt"),
-			[]byte("hello world"),
-			0644); err != nil {
-			t.Fatal(err)
-		}
+	if err := os.WriteFile(
+		filepath.Join(wd, "test.txt"),
+		[]byte("hello world"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"add",
-			"test.txt",
-		); err != nil {
+	for _, args := range [][]string{
+		{"add", "test.txt"},
+		{"commit", "-m", "add test"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
 			t.Fatal(err)
 		}
+	}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"commit",
-			"-m",
-			"add test",
-		); err != nil {
+	seq, err := git.ListCommitChanges(t.Context(), wd, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []string
+	for item, err := range seq {
+		if err != nil {
 			t.Fatal(err)
 		}
 
+		items = append(items, item)
+	}
+
+	if len(items) != 1 || !strings.Contains(items[0], "+hello world") {
+		t.Fatalf("expected the root commit's changes, got %q", items)
+	}
+}
+
+func TestReword(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
 		if err := os.WriteFile(
-			filepath.Join(wd, "test.txt"),
-			[]byte("hello world\nfoo bar"),
+			filepath.Join(wd, name),
+			[]byte(name),
 			0644); err != nil {
 			t.Fatal(err)
 		}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"add",
-			"test.txt",
-		); err != nil {
-			t.Fatal(err)
+		for _, args := range [][]string{
+			{"add", name},
+			{"commit", "-m", fmt.Sprintf("commit %d", i),
+				"--author", "Original Author <original@example.com>",
+				"--date", "2001-02-03T04:05:06Z"},
+		} {
+			if err := runGitCmd(t.Context(), wd, args...); err != nil {
+				t.Fatal(err)
+			}
 		}
+	}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"commit",
-			"-m",
-			"add test",
-		); err != nil {
-			t.Fatal(err)
-		}
+	logOf := func(format string) string {
+		t.Helper()
 
-		hash, err := git.HeadHash(
-			t.Context(),
-			wd,
-		)
-		if err != nil {
+		var buf bytes.Buffer
+		cmd := exec.CommandContext(t.Context(), "git", "log", "--format="+format)
+		cmd.Dir = wd
+		cmd.Stdout = &buf
+		if err := cmd.Run(); err != nil {
 			t.Fatal(err)
 		}
 
-		seq, err := git.CommitsBetween(
+		return strings.TrimSpace(buf.String())
+	}
+
+	trees := logOf("%T")
+
+	// the root commit requires its descendants to be
+	// replayed, HEAD is updated in place
+	for _, reword := range [][2]string{
+		{"HEAD~2", "reworded root"},
+		{"HEAD", "reworded head"},
+	} {
+		if err := git.Reword(
 			t.Context(),
 			wd,
-			[]string{hash},
-		)
-		if err != nil {
+			reword[0],
+			strings.NewReader(reword[1]),
+		); err != nil {
 			t.Fatal(err)
 		}
+	}
 
-		var items []string
-		for item, err := range seq {
-			if err != nil {
-				t.Fatal(err)
-			}
+	if got := logOf("%s"); got != "reworded head\ncommit 1\nreworded root" {
+		t.Fatalf("unexpected history:\n%s", got)
+	}
 
-			items = append(items, item)
-		}
+	if got := logOf("%T"); got != trees {
+		t.Fatal("expected trees to be unchanged")
+	}
 
-		if len(items) != 1 {
-			t.Fatal("bad length")
-		}
+	if got := logOf("%an <%ae> %aI"); strings.Count(
+		got, "Original Author <original@example.com> 2001-02-03T04:05:06+00:00",
+	) != 3 {
+		t.Fatalf("expected authorship to be preserved:\n%s", got)
+	}
 
-		if !strings.Contains(
-			items[0],
-			"+hello world",
-		) {
-			t.Fatal("no commit info")
+	t.Run("unknown ref", func(t *testing.T) {
+		err := git.Reword(t.Context(), wd, "does-not-exist", strings.NewReader("x"))
+		if !errors.Is(err, git.ErrUnknownRef) {
+			t.Fatalf("expected unknown ref error, got %v", err)
 		}
 	})
+}
 
-	t.Run("multi", func(t *testing.T) {
-		wd, err := initNewDir(t.Context())
-		if err != nil {
-			t.Fatal(err)
-		}
+func TestRecentMessages(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		if err := os.WriteFile(
-			filepath.Join(wd, "test.txt"),
-			[]byte("hello world"),
-			0644); err != nil {
+	for _, args := range [][]string{
+		{"commit", "--allow-empty", "-m", "first", "--author", "Other <other@example.com>"},
+		{"checkout", "-q", "-b", "side"},
+		{"commit", "--allow-empty", "-m", "second"},
+		{"checkout", "-q", "-"},
+		{"merge", "--no-ff", "-q", "-m", "merge side", "side"},
+		{"commit", "--allow-empty", "-m", "generated\n\nMessage-generated-by: git-do/0.0.0"},
+		{"commit", "--allow-empty", "-m", "third\n\nWith a body."},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
 			t.Fatal(err)
 		}
+	}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"add",
-			"test.txt",
-		); err != nil {
-			t.Fatal(err)
-		}
+	msgs, err := git.RecentMessages(t.Context(), wd, 10, "Message-generated-by", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"commit",
-			"-m",
-			"add test",
-		); err != nil {
-			t.Fatal(err)
-		}
+	// commits made within the same second have no stable order
+	slices.Sort(msgs)
+	if !slices.Equal(msgs, []string{"first", "second", "third\n\nWith a body."}) {
+		t.Fatalf("unexpected messages: %q", msgs)
+	}
 
-		if err := os.WriteFile(
-			filepath.Join(wd, "test.txt"),
-			[]byte("hello world\nfoo bar"),
-			0644); err != nil {
-			t.Fatal(err)
-		}
+	msgs, err = git.RecentMessages(t.Context(), wd, 1, "Message-generated-by", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		if err := runGitCmd(
-			t.Context(),
-			wd,
-			"add",
-			"test.txt",
-		); err != nil {
-			t.Fatal(err)
+	if len(msgs) != 1 {
+		t.Fatalf("expected count to be respected: %q", msgs)
+	}
+
+	msgs, err = git.RecentMessages(t.Context(), wd, 10, "", []string{"other@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(msgs, []string{"first"}) {
+		t.Fatalf("expected author filter to be respected: %q", msgs)
+	}
+}
+
+func TestInterpretTrailers(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := git.InterpretTrailers(
+		t.Context(),
+		wd,
+		"Add foo\n\nExplain foo.\n\nCloses: PROJ-1\nSigned-off-by: A <a@example.com>\n",
+		"Co-authored-by: B <b@example.com>",
+		"Signed-off-by: A <a@example.com>",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Add foo\n\nExplain foo.\n\n" +
+		"Closes: PROJ-1\nSigned-off-by: A <a@example.com>\nCo-authored-by: B <b@example.com>\n"
+	if msg != expected {
+		t.Fatalf("unexpected message:\n%s", msg)
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"commit", "--allow-empty", "-m", "first"},
+		{"branch", "other"},
+		{"commit", "--allow-empty", "-m", "second"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ok, err := git.IsAncestor(t.Context(), wd, "other", "HEAD"); err != nil || !ok {
+		t.Fatalf("expected other to be an ancestor of HEAD: %v", err)
+	}
+
+	if ok, err := git.IsAncestor(t.Context(), wd, "HEAD", "other"); err != nil || ok {
+		t.Fatalf("expected HEAD to not be an ancestor of other: %v", err)
+	}
+
+	other, err := git.RevParse(t.Context(), wd, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if base, err := git.MergeBase(t.Context(), wd, "HEAD", "other"); err != nil || base != other {
+		t.Fatalf("expected the merge base to be other, got %q: %v", base, err)
+	}
+
+	if upstream, err := git.Upstream(t.Context(), wd); err != nil || len(upstream) > 0 {
+		t.Fatalf("expected no upstream, got %q: %v", upstream, err)
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"checkout", "-q", "-b", "feature/PROJ-1-thing"},
+		{"commit", "--allow-empty", "-m", "first"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if branch, err := git.CurrentBranch(t.Context(), wd); err != nil || branch != "feature/PROJ-1-thing" {
+		t.Fatalf("unexpected branch %q: %v", branch, err)
+	}
+
+	if err := runGitCmd(t.Context(), wd, "checkout", "-q", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+
+	if branch, err := git.CurrentBranch(t.Context(), wd); err != nil || len(branch) > 0 {
+		t.Fatalf("expected no branch when detached, got %q: %v", branch, err)
+	}
+}
+
+func TestParseCommitScope(t *testing.T) {
+	for _, tc := range []struct {
+		args     []string
+		expected git.CommitScope
+	}{
+		{nil, git.CommitScope{}},
+		{[]string{"--no-verify"}, git.CommitScope{}},
+		{[]string{"-a"}, git.CommitScope{All: true}},
+		{[]string{"-am", "msg"}, git.CommitScope{All: true}},
+		{[]string{"-ma", "--all"}, git.CommitScope{All: true}},
+		{[]string{"--author", "a.go", "--", "b.go"}, git.CommitScope{Paths: []string{"b.go"}}},
+		{[]string{"--author=x", "a.go"}, git.CommitScope{Paths: []string{"a.go"}}},
+		{[]string{"-i", "a.go", "b/"}, git.CommitScope{Include: true, Paths: []string{"a.go", "b/"}}},
+		{[]string{"--include", "--only", "a.go"}, git.CommitScope{Paths: []string{"a.go"}}},
+		{[]string{"--", "-weird.go"}, git.CommitScope{Paths: []string{"-weird.go"}}},
+	} {
+		scope := git.ParseCommitScope(tc.args)
+		if scope.All != tc.expected.All ||
+			scope.Include != tc.expected.Include ||
+			!slices.Equal(scope.Paths, tc.expected.Paths) {
+			t.Fatalf("unexpected scope of %q: %+v", tc.args, scope)
+		}
+	}
+}
+
+func TestListCommitting(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(filepath.Join(wd, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.txt", "a")
+	write("b.txt", "b")
+	write("c.txt", "c")
+	for _, args := range [][]string{
+		{"add", "."},
+		{"commit", "-m", "initial"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a is staged, b is modified and c is unchanged.
+	// untracked files are never committed
+	write("a.txt", "staged a")
+	write("b.txt", "modified b")
+	write("untracked.txt", "untracked")
+	if err := runGitCmd(t.Context(), wd, "add", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	files := func(args ...string) []string {
+		t.Helper()
+
+		seq, err := git.ListCommitting(t.Context(), wd, git.ParseCommitScope(args))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var returner []string
+		for patch, err := range seq {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range []string{"a.txt", "b.txt", "c.txt", "untracked.txt"} {
+				if strings.Contains(patch, "+++ b/"+f) {
+					returner = append(returner, f)
+				}
+			}
+		}
+
+		slices.Sort(returner)
+
+		return returner
+	}
+
+	for _, tc := range []struct {
+		args     []string
+		expected []string
+	}{
+		{nil, []string{"a.txt"}},
+		{[]string{"-a"}, []string{"a.txt", "b.txt"}},
+		{[]string{"b.txt"}, []string{"b.txt"}},
+		{[]string{"-o", "--", "b.txt"}, []string{"b.txt"}},
+		{[]string{"-i", "b.txt"}, []string{"a.txt", "b.txt"}},
+	} {
+		if got := files(tc.args...); !slices.Equal(got, tc.expected) {
+			t.Fatalf("expected %q to commit %q, got %q", tc.args, tc.expected, got)
+		}
+	}
+}
+
+func TestListAmending(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(filepath.Join(wd, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) {
+		t.Helper()
+
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := func(args ...string) []string {
+		t.Helper()
+
+		seq, err := git.ListAmending(t.Context(), wd, git.ParseCommitScope(args))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var returner []string
+		for patch, err := range seq {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range []string{"a.txt", "b.txt", "c.txt", "untracked.txt"} {
+				if strings.Contains(patch, "+++ b/"+f) {
+					returner = append(returner, f)
+				}
+			}
+		}
+
+		slices.Sort(returner)
+
+		return returner
+	}
+
+	write("a.txt", "a")
+	write("b.txt", "b")
+	run("add", ".")
+	run("commit", "-m", "initial")
+
+	// the root commit is compared against an empty tree
+	if got := files(); !slices.Equal(got, []string{"a.txt", "b.txt"}) {
+		t.Fatalf("expected the root commit's files, got %q", got)
+	}
+
+	write("c.txt", "c")
+	run("add", "c.txt")
+	run("commit", "-m", "add c")
+
+	// a is staged, b is modified and c is committed at HEAD.
+	// untracked files are never committed
+	write("a.txt", "staged a")
+	write("b.txt", "modified b")
+	write("untracked.txt", "untracked")
+	run("add", "a.txt")
+
+	for _, tc := range []struct {
+		args     []string
+		expected []string
+	}{
+		{nil, []string{"a.txt", "c.txt"}},
+		{[]string{"-a"}, []string{"a.txt", "b.txt", "c.txt"}},
+		{[]string{"b.txt"}, []string{"b.txt", "c.txt"}},
+		{[]string{"-i", "b.txt"}, []string{"a.txt", "b.txt", "c.txt"}},
+	} {
+		if got := files(tc.args...); !slices.Equal(got, tc.expected) {
+			t.Fatalf("expected %q to amend %q, got %q", tc.args, tc.expected, got)
+		}
+	}
+}
+
+func TestListCommits(t *testing.T) {
+	t.Run("single", func(t *testing.T) {
+		wd, err := initNewDir(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(
+			filepath.Join(wd, "test.txt"),
+			[]byte("hello world"),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"add",
+			"test.txt",
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"commit",
+			"-m",
+			"add test",
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(
+			filepath.Join(wd, "test.txt"),
+			[]byte("hello world\nfoo bar"),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"add",
+			"test.txt",
+		); err != nil {
+			t.Fatal(err)
 		}
 
 		if err := runGitCmd(
@@ -898,11 +1397,11 @@ func TestCommitsBetween(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		r, _ := git.RootCommit(t.Context(), wd)
-		seq, err := git.CommitsBetween(
+		seq, err := git.ListCommits(
 			t.Context(),
 			wd,
-			[]string{r, hash},
+			"--no-walk",
+			hash,
 		)
 		if err != nil {
 			t.Fatal(err)
@@ -917,17 +1416,513 @@ func TestCommitsBetween(t *testing.T) {
 			items = append(items, item)
 		}
 
-		if len(items) != 2 {
+		if len(items) != 1 {
 			t.Fatal("bad length")
 		}
 
 		if !strings.Contains(
 			items[0],
-			"+hello world",
+			"+foo bar",
 		) {
 			t.Fatal("no commit info")
 		}
 	})
+
+	t.Run("multi", func(t *testing.T) {
+		wd, err := initNewDir(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(
+			filepath.Join(wd, "test.txt"),
+			[]byte("hello world"),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"add",
+			"test.txt",
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"commit",
+			"-m",
+			"add test",
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(
+			filepath.Join(wd, "test.txt"),
+			[]byte("hello world\nfoo bar"),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"add",
+			"test.txt",
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := runGitCmd(
+			t.Context(),
+			wd,
+			"commit",
+			"-m",
+			"add test",
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := git.HeadHash(
+			t.Context(),
+			wd,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, _ := git.RootCommit(t.Context(), wd)
+		collect := func(args ...string) []string {
+			t.Helper()
+
+			seq, err := git.ListCommits(t.Context(), wd, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var items []string
+			for item, err := range seq {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				items = append(items, item)
+			}
+
+			return items
+		}
+
+		// the range excludes the root commit, as git does
+		items := collect(r + ".." + hash)
+		if len(items) != 1 {
+			t.Fatal("bad length")
+		}
+
+		if !strings.Contains(
+			items[0],
+			"+foo bar",
+		) {
+			t.Fatal("no commit info")
+		}
+
+		items = collect(hash)
+		if len(items) != 2 {
+			t.Fatal("bad length")
+		}
+
+		if !strings.Contains(
+			items[1],
+			"+hello world",
+		) {
+			t.Fatal("no commit info")
+		}
+
+		if items := collect(hash, "--", "other.txt"); len(items) != 0 {
+			t.Fatal("unexpected commits outside paths")
+		}
+	})
+}
+
+func TestLogEntries(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"commit", "--allow-empty", "-m", "first"},
+		{"tag", "v1.0.0"},
+		{"commit", "--allow-empty", "-m", "second", "-m", "Some body.\n\nCloses: #1"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tag, err := git.LatestTag(t.Context(), wd, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tag != "v1.0.0" {
+		t.Fatalf("unexpected tag %q", tag)
+	}
+
+	entries, err := git.LogEntries(t.Context(), wd, tag+"..HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+
+	if len(entries[0].Hash) == 0 ||
+		entries[0].Subject != "second" ||
+		entries[0].Body != "Some body.\n\nCloses: #1" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+}
+
+func TestFileHistory(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(filepath.Join(wd, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("x.txt", "a\nb\nc\n")
+	if err := runGitCmd(t.Context(), wd, "add", "x.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"commit", "-m", "add x"},
+		{"mv", "x.txt", "y.txt"},
+		{"commit", "-m", "rename x to y"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("y.txt", "a\nB\nc\n")
+	write("z.txt", "unrelated\n")
+	for _, args := range [][]string{
+		{"add", "y.txt", "z.txt"},
+		{"commit", "-m", "capitalize b"},
+	} {
+		if err := runGitCmd(t.Context(), wd, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := func(lines string, args ...string) []string {
+		t.Helper()
+
+		seq, err := git.FileHistory(t.Context(), wd, "y.txt", lines, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var commits []string
+		for c, err := range seq {
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			commits = append(commits, c)
+		}
+
+		return commits
+	}
+
+	commits := history("")
+	if len(commits) != 3 {
+		t.Fatalf("expected the file to be followed across its rename, got %d commits", len(commits))
+	}
+
+	for i, expected := range []string{"capitalize b", "rename x to y", "add x"} {
+		if !strings.HasPrefix(commits[i], "commit ") || !strings.Contains(commits[i], expected) {
+			t.Fatalf("expected commit %d to be %q, got:\n%s", i, expected, commits[i])
+		}
+	}
+
+	if strings.Contains(commits[0], "z.txt") {
+		t.Fatal("expected only the changes to the file")
+	}
+
+	if commits := history("", "HEAD~1..HEAD"); len(commits) != 1 {
+		t.Fatalf("expected the args to limit the commits, got %d", len(commits))
+	}
+
+	commits = history("2,2")
+	if len(commits) != 2 ||
+		!strings.Contains(commits[0], "capitalize b") ||
+		!strings.Contains(commits[1], "add x") {
+		t.Fatalf("expected the commits changing the line, got:\n%s", strings.Join(commits, "\n"))
+	}
+}
+
+func TestTree(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(content string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Join(wd, "foo"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(
+			filepath.Join(wd, "foo", "foo.go"),
+			[]byte(content),
+			0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("committed")
+	if err := runGitCmd(t.Context(), wd, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGitCmd(t.Context(), wd, "commit", "-m", "initial"); err != nil {
+		t.Fatal(err)
+	}
+
+	write("staged")
+	if err := runGitCmd(t.Context(), wd, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+
+	write("working")
+
+	head, err := git.TreeAt(t.Context(), wd, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := git.IndexTree(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work, err := git.WorkTree(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for expected, tree := range map[string]*git.Tree{
+		"committed": head,
+		"staged":    index,
+		"working":   work,
+	} {
+		if files := tree.Files("foo"); !slices.Equal(files, []string{"foo/foo.go"}) {
+			t.Fatalf("unexpected files: %v", files)
+		}
+
+		content, err := tree.ReadFile("foo/foo.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(content) != expected {
+			t.Fatalf("expected %q, got %q", expected, content)
+		}
+	}
+
+	files, err := git.CommittingFiles(t.Context(), wd, git.ParseCommitScope([]string{"-a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(files, []string{"foo/foo.go"}) {
+		t.Fatalf("unexpected committing files: %v", files)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GIT_EDITOR", "my-editor --wait")
+
+	editor, err := git.Editor(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if editor != "my-editor --wait" {
+		t.Fatal("unexpected editor")
+	}
+}
+
+func TestEdit(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GIT_EDITOR", "sed -i s/hello/goodbye/")
+
+	edited, err := git.Edit(t.Context(), wd, "hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if edited != "goodbye world" {
+		t.Fatal("unexpected edited content")
+	}
+}
+
+func TestHooksDir(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := git.HooksDir(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(dir, filepath.Join(".git", "hooks")) {
+		t.Fatal("unexpected hooks dir")
+	}
+
+	if err := runGitCmd(
+		t.Context(),
+		wd,
+		"config",
+		"core.hooksPath",
+		".githooks",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err = git.HooksDir(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !filepath.IsAbs(dir) || filepath.Base(dir) != ".githooks" {
+		t.Fatal("expected core.hooksPath to be respected")
+	}
+}
+
+func TestStagedHunks(t *testing.T) {
+	wd, err := initNewDir(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+
+	if err := os.WriteFile(
+		filepath.Join(wd, "test.txt"),
+		[]byte(strings.Join(lines, "\n")+"\n"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGitCmd(t.Context(), wd, "add", "test.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGitCmd(t.Context(), wd, "commit", "-m", "add test"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines[1] = "changed first"
+	lines[28] = "changed last"
+	if err := os.WriteFile(
+		filepath.Join(wd, "test.txt"),
+		[]byte(strings.Join(lines, "\n")+"\n"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(
+		filepath.Join(wd, "new.txt"),
+		[]byte("hello world\n"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGitCmd(t.Context(), wd, "add", "."); err != nil {
+		t.Fatal(err)
+	}
+
+	hunks, err := git.StagedHunks(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hunks) != 3 {
+		t.Fatalf("expected 3 hunks, got %d", len(hunks))
+	}
+
+	if hunks[0].File != "new.txt" || hunks[1].File != "test.txt" {
+		t.Fatal("unexpected hunk files")
+	}
+
+	originalIndex, err := git.WriteTree(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := git.ResetIndex(t.Context(), wd); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := git.ApplyCached(
+		t.Context(),
+		wd,
+		bytes.NewBufferString(git.BuildPatch(hunks[2:])),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	staged, err := git.StagedHunks(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(staged) != 1 || !strings.Contains(staged[0].Body, "+changed last") {
+		t.Fatal("expected only the last hunk to be staged")
+	}
+
+	if err := git.ReadTree(t.Context(), wd, originalIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := git.StagedHunks(t.Context(), wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(restored) != 3 {
+		t.Fatal("expected index to be restored")
+	}
 }
 
 func initNewDir(ctx context.Context) (string, error) {
@@ -0,0 +1,258 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+type (
+	// Hunk of a staged patch that can be applied to the index
+	// independently of the other hunks of its file.
+	Hunk struct {
+		// Index of the hunk within the full patch.
+		Index int
+		// File the hunk belongs to.
+		File string
+		// Header of the file's patch ("diff --git" through "+++").
+		Header string
+		// Body of the hunk. For patches that can not be split
+		// (new, deleted, renamed or binary files) this is the
+		// entire remainder of the file's patch.
+		Body string
+	}
+)
+
+const (
+	filePatchPrefix = "diff --git "
+	hunkPrefix      = "@@ "
+)
+
+// Patch of the hunk, suitable for `git apply`.
+func (recv Hunk) Patch() string {
+	return recv.Header + recv.Body
+}
+
+// Described patch of the hunk, in which the changes of a binary
+// or LFS file are replaced with a description of the file. It is
+// meant to be read, rather than applied.
+func (recv Hunk) Described(ctx context.Context, wd string) string {
+	return describeBinaries(ctx, wd, recv.Patch())
+}
+
+// StagedHunks of the git repo at wd.
+func StagedHunks(ctx context.Context, wd string) ([]Hunk, error) {
+	buf := &bytes.Buffer{}
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		buf,
+		os.Stderr,
+		"diff",
+		"--cached",
+		"--binary",
+		"--no-color",
+		"--no-ext-diff",
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	return ParseHunks(buf.String()), nil
+}
+
+// ParseHunks splits a git patch into its individual hunks.
+func ParseHunks(patch string) []Hunk {
+	var (
+		hunks []Hunk
+		files []string
+	)
+
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		if strings.HasPrefix(line, filePatchPrefix) || len(files) == 0 {
+			files = append(files, line)
+
+			continue
+		}
+
+		files[len(files)-1] += line
+	}
+
+	for _, filePatch := range files {
+		if !strings.HasPrefix(filePatch, filePatchPrefix) {
+			continue
+		}
+
+		header, body := filePatch, ""
+		if i := strings.Index(filePatch, "\n"+hunkPrefix); i >= 0 {
+			header, body = filePatch[:i+1], filePatch[i+1:]
+		}
+
+		file := patchFileName(header)
+		if len(body) == 0 || !isSplittable(header) {
+			hunks = append(hunks, Hunk{
+				Index:  len(hunks),
+				File:   file,
+				Header: header,
+				Body:   body,
+			})
+
+			continue
+		}
+
+		for _, b := range splitHunkBodies(body) {
+			hunks = append(hunks, Hunk{
+				Index:  len(hunks),
+				File:   file,
+				Header: header,
+				Body:   b,
+			})
+		}
+	}
+
+	return hunks
+}
+
+// BuildPatch combines the hunks back into a single patch.
+//
+// The hunks are expected to be in their original order.
+func BuildPatch(hunks []Hunk) string {
+	var (
+		sb         strings.Builder
+		lastHeader string
+	)
+
+	for _, h := range hunks {
+		if h.Header != lastHeader {
+			sb.WriteString(h.Header)
+			lastHeader = h.Header
+		}
+
+		sb.WriteString(h.Body)
+	}
+
+	return sb.String()
+}
+
+// ApplyCached applies the patch to the index of the git repo at wd,
+// leaving the working tree untouched.
+func ApplyCached(ctx context.Context, wd string, patch io.Reader) error {
+	cmd := prepareGitCmd(
+		ctx,
+		wd,
+		os.Stdout,
+		os.Stderr,
+		"apply",
+		"--cached",
+		"-",
+	)
+	cmd.Stdin = patch
+
+	return cmd.Run()
+}
+
+// WriteTree of the current index of the git repo at wd.
+//
+// The returned tree hash may be used with ReadTree to restore the index.
+func WriteTree(ctx context.Context, wd string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"write-tree",
+	).Run(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(
+		dst.String(),
+	), nil
+}
+
+// ReadTree replaces the index of the git repo at wd
+// with the contents of tree.
+func ReadTree(ctx context.Context, wd, tree string) error {
+	return prepareGitCmd(
+		ctx,
+		wd,
+		os.Stdout,
+		os.Stderr,
+		"read-tree",
+		tree,
+	).Run()
+}
+
+// ResetIndex of the git repo at wd to match HEAD.
+//
+// If there are no commits yet, the index is emptied.
+func ResetIndex(ctx context.Context, wd string) error {
+	if _, err := HeadHash(ctx, wd); err != nil {
+		return prepareGitCmd(
+			ctx,
+			wd,
+			os.Stdout,
+			os.Stderr,
+			"read-tree",
+			"--empty",
+		).Run()
+	}
+
+	return ReadTree(ctx, wd, "HEAD")
+}
+
+// isSplittable reports whether the hunks of the file patch
+// with the provided header can be applied independently.
+func isSplittable(header string) bool {
+	for _, marker := range []string{
+		"new file mode",
+		"deleted file mode",
+		"rename from",
+		"copy from",
+		"old mode",
+		"Binary files",
+		"GIT binary patch",
+	} {
+		if strings.Contains(header, marker) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func splitHunkBodies(body string) []string {
+	var bodies []string
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if strings.HasPrefix(line, hunkPrefix) || len(bodies) == 0 {
+			bodies = append(bodies, line)
+
+			continue
+		}
+
+		bodies[len(bodies)-1] += line
+	}
+
+	return bodies
+}
+
+func patchFileName(header string) string {
+	var fromDiffLine string
+	for _, line := range strings.Split(header, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ b/"):
+			return strings.TrimPrefix(line, "+++ b/")
+		case strings.HasPrefix(line, "rename to "):
+			return strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, filePatchPrefix):
+			// "diff --git a/<path> b/<path>"
+			if i := strings.LastIndex(line, " b/"); i >= 0 {
+				fromDiffLine = line[i+len(" b/"):]
+			}
+		}
+	}
+
+	return fromDiffLine
+}
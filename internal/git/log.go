@@ -0,0 +1,192 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+)
+
+type (
+	// LogEntry of a commit, as reported by `git log`.
+	LogEntry struct {
+		Hash    string
+		Subject string
+		// Body of the message, following the subject,
+		// including any trailers.
+		Body string
+	}
+)
+
+const (
+	logFieldSeparator  = "\x1f"
+	logRecordSeparator = "\x1e"
+	logCommitPrefix    = "commit "
+)
+
+// LogEntries reported by `git log` given the args, which may be
+// anything it accepts (e.g. "v1.0..HEAD" or "--no-merges").
+func LogEntries(ctx context.Context, wd string, args ...string) ([]LogEntry, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		slices.Concat([]string{
+			"log",
+			"--format=%H%x1f%s%x1f%b%x1e",
+		}, args)...,
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	var entries []LogEntry
+	for record := range strings.SplitSeq(dst.String(), logRecordSeparator) {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), logFieldSeparator)
+		if len(fields) != 3 {
+			continue
+		}
+
+		entries = append(entries, LogEntry{
+			Hash:    fields[0],
+			Subject: fields[1],
+			Body:    strings.TrimSpace(fields[2]),
+		})
+	}
+
+	return entries, nil
+}
+
+// LatestTag reachable from rev, if any.
+func LatestTag(ctx context.Context, wd, rev string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		nil,
+		"describe",
+		"--tags",
+		"--abbrev=0",
+		rev,
+	).Run(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(dst.String()), nil
+}
+
+// Tags reachable from rev, the most recent version first.
+func Tags(ctx context.Context, wd, rev string) ([]string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"tag",
+		"--list",
+		"--merged",
+		rev,
+		"--sort=-v:refname",
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(dst.String()), nil
+}
+
+// CreateTag as an annotated tag of HEAD, with the message verbatim.
+func CreateTag(ctx context.Context, wd, name string, msg io.Reader) error {
+	cmd := prepareGitCmd(
+		ctx,
+		wd,
+		os.Stdout,
+		os.Stderr,
+		"tag",
+		"--annotate",
+		"--cleanup=verbatim",
+		"--file=-",
+		name,
+	)
+	cmd.Stdin = msg
+
+	return cmd.Run()
+}
+
+// FileHistory provides an iterator to step through each commit
+// changing the file, newest first, along with its changes to the
+// file. The file is followed across renames.
+//
+// If lines is provided (e.g. "10,20" or ":<funcname>"), only the
+// commits changing those lines of the file are included. Any args
+// are passed to `git log` (e.g. "--author=<pattern>" or "A..B").
+func FileHistory(
+	ctx context.Context,
+	wd,
+	file,
+	lines string,
+	args ...string,
+) (iter.Seq2[string, error], error) {
+	cmdLine := []string{"log", "--pretty=medium", "--no-decorate"}
+	if len(lines) > 0 {
+		// -L follows the lines across renames itself,
+		// and may not be combined with pathspecs
+		cmdLine = slices.Concat(cmdLine, []string{"-L", lines + ":" + file}, args)
+	} else {
+		cmdLine = slices.Concat(cmdLine, []string{"--follow", "--patch"}, args, []string{"--", file})
+	}
+
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		cmdLine...,
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	commits := splitLog(dst.String())
+
+	return func(yield func(string, error) bool) {
+		for _, c := range commits {
+			if !yield(describeBinaries(ctx, wd, c), nil) {
+				return
+			}
+		}
+	}, nil
+}
+
+// splitLog of `git log` into each commit. Lines of messages
+// and patches are indented or prefixed, so only the header of
+// a commit may start with "commit ".
+func splitLog(log string) []string {
+	var (
+		commits []string
+		start   = -1
+		offset  int
+	)
+
+	for line := range strings.SplitAfterSeq(log, "\n") {
+		if strings.HasPrefix(line, logCommitPrefix) {
+			if start >= 0 {
+				commits = append(commits, log[start:offset])
+			}
+			start = offset
+		}
+
+		offset += len(line)
+	}
+
+	if start >= 0 {
+		commits = append(commits, log[start:])
+	}
+
+	return commits
+}
@@ -0,0 +1,293 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+)
+
+type (
+	// CommitScope describes which changes a `git commit`
+	// invocation will commit.
+	CommitScope struct {
+		// All tracked changes in the working tree (-a, --all).
+		All bool
+		// Include the Paths' working tree changes alongside
+		// the staged changes (-i, --include).
+		Include bool
+		// Paths whose working tree changes are committed. Unless
+		// Include is set, only these paths are committed (-o, --only).
+		Paths []string
+	}
+)
+
+var (
+	// commitValueFlags of `git commit` that take a separate value.
+	commitValueFlags = []string{
+		"-m", "--message",
+		"-F", "--file",
+		"-C", "--reuse-message",
+		"-c", "--reedit-message",
+		"-t", "--template",
+		"--author",
+		"--date",
+		"--cleanup",
+		"--fixup",
+		"--squash",
+		"--trailer",
+		"--pathspec-from-file",
+	}
+	// commitValueShortFlags of `git commit` that take a value,
+	// either attached or as the following argument.
+	commitValueShortFlags = "mFCct"
+)
+
+// ParseCommitScope from the arguments that will be passed to `git commit`.
+func ParseCommitScope(args []string) CommitScope {
+	var (
+		returner    CommitScope
+		onlyOptions = true
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case !onlyOptions || !strings.HasPrefix(arg, "-") || arg == "-":
+			returner.Paths = append(returner.Paths, arg)
+		case arg == "--":
+			onlyOptions = false
+		case arg == "--all":
+			returner.All = true
+		case arg == "--include":
+			returner.Include = true
+		case arg == "--only":
+			returner.Include = false
+		case strings.HasPrefix(arg, "--"):
+			if !strings.Contains(arg, "=") && slices.Contains(commitValueFlags, arg) {
+				// skip the value
+				i++
+			}
+		default:
+			// a cluster of short flags, e.g. -am
+		cluster:
+			for j, flag := range arg[1:] {
+				switch flag {
+				case 'a':
+					returner.All = true
+				case 'i':
+					returner.Include = true
+				case 'o':
+					returner.Include = false
+				}
+
+				if strings.ContainsRune(commitValueShortFlags, flag) {
+					if j == len(arg)-2 {
+						// the value is the following argument
+						i++
+					}
+
+					break cluster
+				}
+			}
+		}
+	}
+
+	return returner
+}
+
+// Staged reports whether the scope is exactly the staged changes.
+func (recv CommitScope) Staged() bool {
+	return !recv.All && len(recv.Paths) == 0
+}
+
+// ListCommitting provides an iterator to step through the changes
+// to each file that `git commit` will commit, given the scope.
+func ListCommitting(
+	ctx context.Context,
+	wd string,
+	scope CommitScope,
+) (iter.Seq2[string, error], error) {
+	if scope.Staged() {
+		return ListStaged(ctx, wd)
+	}
+
+	base, err := HeadHash(ctx, wd)
+	if err != nil {
+		// nothing has been committed yet
+		base, err = hashDevNull(ctx, wd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files whose working tree content will be committed
+	worktreeFiles, err := changedFiles(
+		ctx, wd,
+		slices.Concat([]string{base, "--"}, scope.Paths)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// files whose staged content will be committed
+	var stagedFiles []string
+	if scope.Include && !scope.All {
+		stagedFiles, err = changedFiles(ctx, wd, "--cached")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(yield func(string, error) bool) {
+		for _, f := range worktreeFiles {
+			diffs := &bytes.Buffer{}
+			err := prepareGitCmd(
+				ctx,
+				wd,
+				diffs,
+				os.Stderr,
+				"diff",
+				"--unified=12",
+				"--raw",
+				base,
+				"--",
+				f,
+			).Run()
+
+			if !yield(describeBinaries(ctx, wd, diffs.String()), err) {
+				return
+			}
+		}
+
+		for _, f := range stagedFiles {
+			if slices.Contains(worktreeFiles, f) {
+				continue
+			}
+
+			diffs := &bytes.Buffer{}
+			err := StagedDiffs(ctx, wd, f, diffs)
+
+			if !yield(describeBinaries(ctx, wd, diffs.String()), err) {
+				return
+			}
+		}
+	}, nil
+}
+
+// ListAmending provides an iterator to step through the changes to
+// each file that `git commit --amend` will commit, given the scope.
+//
+// The changes are relative to the parent of HEAD, so those already
+// committed at HEAD are included alongside the scope's changes.
+func ListAmending(
+	ctx context.Context,
+	wd string,
+	scope CommitScope,
+) (iter.Seq2[string, error], error) {
+	parent, err := RevParse(ctx, wd, "HEAD^")
+	if err != nil {
+		// HEAD is the root commit
+		parent, err = hashDevNull(ctx, wd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files whose working tree content will be committed
+	var worktreeFiles []string
+	if !scope.Staged() {
+		worktreeFiles, err = changedFiles(
+			ctx, wd,
+			slices.Concat([]string{"HEAD", "--"}, scope.Paths)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files whose staged content will be committed
+	var stagedFiles []string
+	if scope.Staged() || (scope.Include && !scope.All) {
+		stagedFiles, err = changedFiles(ctx, wd, "--cached")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// files committed at HEAD, which are kept as they are
+	// unless they are also among the scope's changes
+	headFiles, err := changedFiles(ctx, wd, parent, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	diff := func(args ...string) (string, error) {
+		diffs := &bytes.Buffer{}
+		err := prepareGitCmd(
+			ctx,
+			wd,
+			diffs,
+			os.Stderr,
+			slices.Concat([]string{"diff", "--unified=12", "--raw"}, args)...,
+		).Run()
+
+		return describeBinaries(ctx, wd, diffs.String()), err
+	}
+
+	return func(yield func(string, error) bool) {
+		var listed []string
+		for _, f := range worktreeFiles {
+			listed = append(listed, f)
+			if !yield(diff(parent, "--", f)) {
+				return
+			}
+		}
+
+		for _, f := range stagedFiles {
+			if slices.Contains(listed, f) {
+				continue
+			}
+
+			listed = append(listed, f)
+			if !yield(diff("--cached", parent, "--", f)) {
+				return
+			}
+		}
+
+		for _, f := range headFiles {
+			if slices.Contains(listed, f) {
+				continue
+			}
+
+			if !yield(diff(parent, "HEAD", "--", f)) {
+				return
+			}
+		}
+	}, nil
+}
+
+func changedFiles(ctx context.Context, wd string, args ...string) ([]string, error) {
+	fileList := &bytes.Buffer{}
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		fileList,
+		os.Stderr,
+		slices.Concat([]string{"diff", "--name-only"}, args)...,
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	var returner []string
+	scanner := bufio.NewScanner(fileList)
+	for scanner.Scan() {
+		returner = append(returner, scanner.Text())
+	}
+
+	return returner, scanner.Err()
+}
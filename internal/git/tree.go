@@ -0,0 +1,235 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type (
+	// Tree of the repo's files at a revision, in the index
+	// or in the working tree. Paths are relative to the root
+	// of the repo and always use forward slashes.
+	Tree struct {
+		// blobs of each file, by path. Files of the
+		// working tree have no blob.
+		blobs map[string]string
+		read  func(name, blob string) ([]byte, error)
+	}
+)
+
+// TreeAt the revision.
+func TreeAt(ctx context.Context, wd, rev string) (*Tree, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"ls-tree",
+		"-r",
+		"-z",
+		"--full-tree",
+		rev,
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	blobs := map[string]string{}
+	for entry := range strings.SplitSeq(dst.String(), "\x00") {
+		// "<mode> <type> <hash>\t<path>"
+		info, name, found := strings.Cut(entry, "\t")
+		if fields := strings.Fields(info); found && len(fields) == 3 && fields[1] == "blob" {
+			blobs[name] = fields[2]
+		}
+	}
+
+	return &Tree{
+		blobs: blobs,
+		read:  blobReader(ctx, wd),
+	}, nil
+}
+
+// IndexTree of the staged files.
+func IndexTree(ctx context.Context, wd string) (*Tree, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"ls-files",
+		"-z",
+		"--stage",
+		"--full-name",
+		":/",
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	blobs := map[string]string{}
+	for entry := range strings.SplitSeq(dst.String(), "\x00") {
+		// "<mode> <hash> <stage>\t<path>"
+		info, name, found := strings.Cut(entry, "\t")
+		if fields := strings.Fields(info); found && len(fields) == 3 {
+			blobs[name] = fields[1]
+		}
+	}
+
+	return &Tree{
+		blobs: blobs,
+		read:  blobReader(ctx, wd),
+	}, nil
+}
+
+// WorkTree of the tracked files, as they are on disk.
+func WorkTree(ctx context.Context, wd string) (*Tree, error) {
+	root, err := topLevel(ctx, wd)
+	if err != nil {
+		return nil, err
+	}
+
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"ls-files",
+		"-z",
+		"--full-name",
+		":/",
+	).Run(); err != nil {
+		return nil, err
+	}
+
+	blobs := map[string]string{}
+	for name := range strings.SplitSeq(dst.String(), "\x00") {
+		if len(name) > 0 {
+			blobs[name] = ""
+		}
+	}
+
+	return &Tree{
+		blobs: blobs,
+		read: func(name, _ string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		},
+	}, nil
+}
+
+// Files directly within dir.
+func (recv *Tree) Files(dir string) []string {
+	var files []string
+	for name := range recv.blobs {
+		if path.Dir(name) == dir {
+			files = append(files, name)
+		}
+	}
+
+	slices.Sort(files)
+
+	return files
+}
+
+func (recv *Tree) ReadFile(name string) ([]byte, error) {
+	blob, found := recv.blobs[name]
+	if !found {
+		return nil, os.ErrNotExist
+	}
+
+	return recv.read(name, blob)
+}
+
+// ChangedFiles between the revisions. An empty from
+// includes every file of the to revision.
+func ChangedFiles(ctx context.Context, wd, from, to string) ([]string, error) {
+	if len(from) == 0 {
+		var err error
+		from, err = hashDevNull(ctx, wd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changedFiles(ctx, wd, from, to)
+}
+
+// CommittingFiles that `git commit` will commit, given the scope.
+func CommittingFiles(ctx context.Context, wd string, scope CommitScope) ([]string, error) {
+	if scope.Staged() {
+		return changedFiles(ctx, wd, "--cached")
+	}
+
+	base, err := HeadHash(ctx, wd)
+	if err != nil {
+		// nothing has been committed yet
+		base, err = hashDevNull(ctx, wd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := changedFiles(
+		ctx, wd,
+		slices.Concat([]string{base, "--"}, scope.Paths)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope.Include && !scope.All {
+		staged, err := changedFiles(ctx, wd, "--cached")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range staged {
+			if !slices.Contains(files, f) {
+				files = append(files, f)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+func blobReader(ctx context.Context, wd string) func(name, blob string) ([]byte, error) {
+	return func(_, blob string) ([]byte, error) {
+		var dst bytes.Buffer
+		if err := prepareGitCmd(
+			ctx,
+			wd,
+			&dst,
+			os.Stderr,
+			"cat-file",
+			"blob",
+			blob,
+		).Run(); err != nil {
+			return nil, err
+		}
+
+		return dst.Bytes(), nil
+	}
+}
+
+// topLevel directory of the repo's working tree.
+func topLevel(ctx context.Context, wd string) (string, error) {
+	var dst bytes.Buffer
+	if err := prepareGitCmd(
+		ctx,
+		wd,
+		&dst,
+		os.Stderr,
+		"rev-parse",
+		"--show-toplevel",
+	).Run(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(dst.String()), nil
+}
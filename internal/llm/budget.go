@@ -0,0 +1,266 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/rs/zerolog/log"
+)
+
+type (
+	// BudgetStrategy determines how a file's patch is reduced
+	// when it does not fit within its share of the token budget.
+	BudgetStrategy string
+
+	summarizeInstructionsTemplateData struct {
+		Language string
+	}
+)
+
+const (
+	// BudgetStrategyTruncate cuts the patch off once its share is exhausted.
+	BudgetStrategyTruncate = BudgetStrategy("truncate")
+	// BudgetStrategyStat replaces the patch with its header,
+	// hunk headers and a count of changed lines.
+	BudgetStrategyStat = BudgetStrategy("stat")
+	// BudgetStrategySummarize replaces the patch with a summary
+	// generated by a separate, preliminary request.
+	BudgetStrategySummarize = BudgetStrategy("summarize")
+
+	// bytesPerToken is a rough approximation that holds well
+	// enough for source code and diffs.
+	bytesPerToken = 4
+)
+
+// Valid reports whether the strategy is known.
+func (recv BudgetStrategy) Valid() bool {
+	switch recv {
+	case BudgetStrategyTruncate,
+		BudgetStrategyStat,
+		BudgetStrategySummarize:
+		return true
+	}
+
+	return false
+}
+
+// fitBudget reduces the patches, if required, so their combined
+// size fits within the configured token budget.
+//
+// Smaller patches are always preferred to be kept whole. The budget
+// left over is then shared equally between the larger patches.
+func (recv *LLM) fitBudget(
+	ctx context.Context,
+	patches []string,
+) ([]string, error) {
+	budget := recv.config.budgetTokens
+	if budget <= 0 {
+		return patches, nil
+	}
+
+	var total int
+	for _, p := range patches {
+		total += estimateTokens(p)
+	}
+
+	if total <= budget {
+		return patches, nil
+	}
+
+	log.Debug().
+		Int("tokens", total).
+		Int("budget", budget).
+		Msg("patches exceed budget")
+
+	shares := fairShares(patches, budget)
+	fitted := slices.Clone(patches)
+	for i, p := range patches {
+		size := estimateTokens(p)
+		if size <= shares[i] {
+			continue
+		}
+
+		reduced, strategy, err := recv.reducePatch(ctx, p, shares[i])
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debug().
+			Str("file", patchFileName(p)).
+			Int("tokens", size).
+			Int("share", shares[i]).
+			Int("reduced_tokens", estimateTokens(reduced)).
+			Str("strategy", string(strategy)).
+			Msg("reduced patch to fit budget")
+
+		fitted[i] = reduced
+	}
+
+	return fitted, nil
+}
+
+func (recv *LLM) reducePatch(
+	ctx context.Context,
+	patch string,
+	share int,
+) (string, BudgetStrategy, error) {
+	strategy := recv.config.budgetStrategy
+	switch strategy {
+	case BudgetStrategyTruncate:
+		return truncatePatch(patch, share), strategy, nil
+	case BudgetStrategySummarize:
+		summary, err := recv.summarizePatch(ctx, patch)
+		if err == nil {
+			return truncatePatch(summary, share), strategy, nil
+		}
+
+		log.Debug().
+			Err(err).
+			Str("file", patchFileName(patch)).
+			Msg("failed to summarize patch, falling back to stat")
+	}
+
+	return truncatePatch(statPatch(patch), share), BudgetStrategyStat, nil
+}
+
+// summarizePatch in a preliminary request. The patch is truncated
+// to the overall budget so that the summary request is itself bounded.
+func (recv *LLM) summarizePatch(
+	ctx context.Context,
+	patch string,
+) (string, error) {
+	instructionData := &summarizeInstructionsTemplateData{
+		Language: defaultLang.String(),
+	}
+
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
+
+	instructions, err := execInstructionTmpl(
+		summarizeInstructions,
+		instructionData,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	summary, err := recv.complete(ctx, recv.newParams(
+		instructions,
+		responses.ResponseInputParam{
+			stringResponseItem(truncatePatch(patch, recv.config.budgetTokens)),
+			stringResponseItem("GENERATE"),
+		},
+	), true)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%sSUMMARY OF CHANGES (the full patch was omitted for size)\n%s\n",
+		patchHeader(patch),
+		strings.TrimSpace(summary),
+	), nil
+}
+
+// fairShares of the budget for each patch.
+func fairShares(patches []string, budget int) []int {
+	order := make([]int, len(patches))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortFunc(order, func(a, b int) int {
+		return len(patches[a]) - len(patches[b])
+	})
+
+	shares := make([]int, len(patches))
+	remaining := budget
+	for n, i := range order {
+		share := remaining / (len(order) - n)
+		if size := estimateTokens(patches[i]); size < share {
+			share = size
+		}
+
+		shares[i] = share
+		remaining -= share
+	}
+
+	return shares
+}
+
+func estimateTokens(s string) int {
+	return (len(s) + bytesPerToken - 1) / bytesPerToken
+}
+
+// truncatePatch to approximately the provided number of tokens,
+// cutting on a line boundary.
+func truncatePatch(patch string, tokens int) string {
+	limit := tokens * bytesPerToken
+	if len(patch) <= limit {
+		return patch
+	}
+
+	cut := strings.LastIndex(patch[:limit], "\n") + 1
+	omitted := strings.Count(patch[cut:], "\n")
+
+	return fmt.Sprintf("%s[%d more lines truncated]\n", patch[:cut], omitted)
+}
+
+// statPatch reduces the patch to its header, hunk headers
+// and a count of the lines changed.
+func statPatch(patch string) string {
+	var (
+		sb                    strings.Builder
+		insertions, deletions int
+	)
+
+	sb.WriteString(patchHeader(patch))
+
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		case strings.HasPrefix(line, "+++"),
+			strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			insertions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+
+	fmt.Fprintf(&sb,
+		"%d insertions(+), %d deletions(-) (hunk contents omitted for size)\n",
+		insertions,
+		deletions,
+	)
+
+	return sb.String()
+}
+
+// patchHeader is everything before the first hunk.
+func patchHeader(patch string) string {
+	if i := strings.Index(patch, "\n@@"); i >= 0 {
+		return patch[:i+1]
+	}
+
+	return patch
+}
+
+func patchFileName(patch string) string {
+	for _, line := range strings.Split(patch, "\n") {
+		if after, found := strings.CutPrefix(line, "diff --git "); found {
+			if i := strings.LastIndex(after, " b/"); i >= 0 {
+				return after[i+len(" b/"):]
+			}
+
+			return after
+		}
+	}
+
+	return ""
+}
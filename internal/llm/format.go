@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"text/template"
+)
+
+type (
+	// BodyRule determines whether a commit body is expected.
+	BodyRule string
+
+	// FormatSpec describes a user-defined commit format.
+	FormatSpec struct {
+		// Name of the format.
+		Name string
+		// Rules describing the format to the model. These are
+		// parsed as a template with access to the Language.
+		Rules string
+		// TitleMaxLength of the first line. Zero for no limit.
+		TitleMaxLength int
+		// Body requirement of the format.
+		Body BodyRule
+		// Trailers required to end every message.
+		Trailers []string
+	}
+
+	customFormatTemplateData struct {
+		Name           string
+		Rules          string
+		TitleMaxLength int
+		Body           BodyRule
+		Trailers       []string
+	}
+)
+
+const (
+	BodyRuleOptional = BodyRule("optional")
+	BodyRuleRequired = BodyRule("required")
+	BodyRuleNone     = BodyRule("none")
+)
+
+// Valid reports whether the rule is known. An empty
+// rule is treated as optional.
+func (recv BodyRule) Valid() bool {
+	switch recv {
+	case "",
+		BodyRuleOptional,
+		BodyRuleRequired,
+		BodyRuleNone:
+		return true
+	}
+
+	return false
+}
+
+// templateData of the format, with its rules rendered
+// for the provided language.
+func (recv *FormatSpec) templateData(lang string) (*customFormatTemplateData, error) {
+	t, err := template.New(recv.Name).Parse(recv.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := execInstructionTmpl(t, struct {
+		Language string
+	}{
+		Language: lang,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := recv.Body
+	if len(body) == 0 {
+		body = BodyRuleOptional
+	}
+
+	return &customFormatTemplateData{
+		Name:           recv.Name,
+		Rules:          rules,
+		TitleMaxLength: recv.TitleMaxLength,
+		Body:           body,
+		Trailers:       recv.Trailers,
+	}, nil
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+type (
+	// Issue referenced by the changes, as described
+	// by an issue tracker.
+	Issue struct {
+		// Ref of the issue, as provided by the user.
+		Ref         string
+		Title       string
+		Description string
+	}
+)
+
+const (
+	// issueDescriptionTokens limits each issue's description,
+	// they are often far longer than what is useful.
+	issueDescriptionTokens = 500
+)
+
+// issuesResponseItem describing each of the issues.
+func issuesResponseItem(issues []Issue) responses.ResponseInputItemUnionParam {
+	var sb strings.Builder
+	sb.WriteString("ISSUES")
+
+	for _, i := range issues {
+		fmt.Fprintf(&sb, "\n%s\nREF: %s\nTITLE: %s\nDESCRIPTION:\n%s",
+			examplesSeparator,
+			i.Ref,
+			strings.TrimSpace(i.Title),
+			strings.TrimSpace(truncatePatch(i.Description, issueDescriptionTokens)),
+		)
+	}
+
+	return stringResponseItem(sb.String())
+}
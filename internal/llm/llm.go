@@ -3,11 +3,13 @@ package llm
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"iter"
 	"net/http"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -15,6 +17,7 @@ import (
 	_ "embed"
 
 	tld "github.com/jpillora/go-tld"
+	"github.com/julianwyz/git-do/internal/cache"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/param"
@@ -36,10 +39,72 @@ type (
 	commitInstructionsTemplateData struct {
 		Language string
 		Format   string
+		Custom   *customFormatTemplateData
+		// Scopes allowed by conventional commits.
+		Scopes []string
 	}
 
 	explanationInstructionsTemplateData struct {
 		Language string
+		// Structured output, rather than prose.
+		Structured bool
+		// File whose history is explained, if any,
+		// and the Lines of it the history is limited to.
+		File  string
+		Lines string
+	}
+
+	// Explanation of a set of changes, as structured output.
+	Explanation struct {
+		// Summary of all the changes, in prose.
+		Summary string `json:"summary"`
+		// Commits explained individually, empty
+		// when explaining uncommitted changes.
+		Commits []CommitExplanation `json:"commits"`
+		// Areas of the project affected by the changes.
+		Areas []string `json:"areas"`
+		// UserFacingChanges noticeable by users of the project.
+		UserFacingChanges []string `json:"user_facing_changes"`
+		// Risks reviewers should be aware of.
+		Risks []string `json:"risks"`
+		// Issues referenced by the changes, verbatim.
+		Issues []string `json:"issues"`
+	}
+
+	// CommitExplanation summarizes a single commit.
+	CommitExplanation struct {
+		Hash    string `json:"hash"`
+		Summary string `json:"summary"`
+	}
+
+	splitInstructionsTemplateData struct {
+		Language string
+	}
+
+	releaseInstructionsTemplateData struct {
+		Language string
+	}
+
+	pullRequestInstructionsTemplateData struct {
+		Language string
+	}
+
+	// PullRequest title and description.
+	PullRequest struct {
+		Title string `json:"title"`
+		// Body of the description, in Markdown.
+		Body string `json:"body"`
+	}
+
+	categorizeInstructionsTemplateData struct {
+		Categories []string
+	}
+
+	// ChangeGroup is a set of hunks that make up a single,
+	// atomic commit.
+	ChangeGroup struct {
+		Summary string `json:"summary"`
+		Hunks   []int  `json:"hunks"`
 	}
 
 	statusInstructionsTemplateData struct {
@@ -50,11 +115,20 @@ type (
 	contextLoader interface {
 		LoadContextFile() (io.ReadCloser, error)
 	}
+
+	responseCache interface {
+		Get(key string) ([]byte, bool)
+		Put(key string, content []byte) error
+	}
 )
 
 const (
-	defaultModel        = "gpt-5-mini"
+	// DefaultModel used when no model is configured.
+	DefaultModel        = "gpt-5-mini"
 	defaultCommitFormat = "github"
+	examplesSeparator   = "---"
+	// omitScope when the changes span several scopes
+	omitScope = "OMIT"
 )
 
 const (
@@ -67,7 +141,101 @@ const (
 )
 
 var (
-	ErrNoPatches = errors.New("no changes to commit")
+	ErrNoPatches       = errors.New("no changes to commit")
+	ErrNoCandidates    = errors.New("no commit candidates generated")
+	ErrMalformedOutput = errors.New("llm output does not match the expected structure")
+
+	candidatesSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"candidates": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "string",
+				},
+			},
+		},
+		"required":             []string{"candidates"},
+		"additionalProperties": false,
+	}
+	explanationSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary": map[string]any{
+				"type": "string",
+			},
+			"commits": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"hash": map[string]any{
+							"type": "string",
+						},
+						"summary": map[string]any{
+							"type": "string",
+						},
+					},
+					"required":             []string{"hash", "summary"},
+					"additionalProperties": false,
+				},
+			},
+			"areas":               stringsSchema,
+			"user_facing_changes": stringsSchema,
+			"risks":               stringsSchema,
+			"issues":              stringsSchema,
+		},
+		"required": []string{
+			"summary", "commits", "areas",
+			"user_facing_changes", "risks", "issues",
+		},
+		"additionalProperties": false,
+	}
+	stringsSchema = map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"type": "string",
+		},
+	}
+	pullRequestSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"title": map[string]any{
+				"type": "string",
+			},
+			"body": map[string]any{
+				"type": "string",
+			},
+		},
+		"required":             []string{"title", "body"},
+		"additionalProperties": false,
+	}
+	changeGroupsSchema = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"groups": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"summary": map[string]any{
+							"type": "string",
+						},
+						"hunks": map[string]any{
+							"type": "array",
+							"items": map[string]any{
+								"type": "integer",
+							},
+						},
+					},
+					"required":             []string{"summary", "hunks"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"groups"},
+		"additionalProperties": false,
+	}
 
 	defaultLang = language.AmericanEnglish
 	//go:embed prompts/gen_commit_instruct.tmpl.md
@@ -90,6 +258,66 @@ var (
 
 		return t
 	}()
+	//go:embed prompts/explain_diff_instruct.tmpl.md
+	explainDiffInstSrc      string
+	explainDiffInstructions = func() *template.Template {
+		t, err := template.New("explain_diff_instruct.tmpl.md").Parse(explainDiffInstSrc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse diff explanation instruction template")
+		}
+
+		return t
+	}()
+	//go:embed prompts/split_instruct.tmpl.md
+	splitInstSrc      string
+	splitInstructions = func() *template.Template {
+		t, err := template.New("split_instruct.tmpl.md").Parse(splitInstSrc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse split instruction template")
+		}
+
+		return t
+	}()
+	//go:embed prompts/categorize_instruct.tmpl.md
+	categorizeInstSrc      string
+	categorizeInstructions = func() *template.Template {
+		t, err := template.New("categorize_instruct.tmpl.md").Parse(categorizeInstSrc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse categorize instruction template")
+		}
+
+		return t
+	}()
+	//go:embed prompts/release_instruct.tmpl.md
+	releaseInstSrc      string
+	releaseInstructions = func() *template.Template {
+		t, err := template.New("release_instruct.tmpl.md").Parse(releaseInstSrc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse release instruction template")
+		}
+
+		return t
+	}()
+	//go:embed prompts/pull_request_instruct.tmpl.md
+	pullRequestInstSrc      string
+	pullRequestInstructions = func() *template.Template {
+		t, err := template.New("pull_request_instruct.tmpl.md").Parse(pullRequestInstSrc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse pull request instruction template")
+		}
+
+		return t
+	}()
+	//go:embed prompts/summarize_instruct.tmpl.md
+	summarizeInstSrc      string
+	summarizeInstructions = func() *template.Template {
+		t, err := template.New("summarize_instruct.tmpl.md").Parse(summarizeInstSrc)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to parse summarize instruction template")
+		}
+
+		return t
+	}()
 	//go:embed prompts/status_instruct.tmpl.md
 	statusInstSrc      string
 	statusInstructions = func() *template.Template {
@@ -106,8 +334,9 @@ func New(
 	opts ...LLMOpt,
 ) (*LLM, error) {
 	config := &llmConfig{
-		model: defaultModel,
-		http:  http.DefaultClient,
+		model:             DefaultModel,
+		http:              http.DefaultClient,
+		validationRetries: defaultValidationRetries,
 	}
 	for _, o := range opts {
 		if err := o(config); err != nil {
@@ -137,14 +366,628 @@ func New(
 	}, nil
 }
 
-func (recv *LLM) ExplainCommits(
+func (recv *LLM) ExplainCommits(
+	ctx context.Context,
+	commits iter.Seq2[string, error],
+	dst io.Writer,
+	opts ...ExplainOpt,
+) error {
+	respParams, err := recv.explainCommitsRequest(ctx, commits, false, opts...)
+	if err != nil {
+		return err
+	}
+
+	return recv.stream(ctx, respParams, dst)
+}
+
+// ExplainCommitsStructured explains the commits as
+// structured output, rather than prose.
+func (recv *LLM) ExplainCommitsStructured(
+	ctx context.Context,
+	commits iter.Seq2[string, error],
+	opts ...ExplainOpt,
+) (*Explanation, error) {
+	respParams, err := recv.explainCommitsRequest(ctx, commits, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return recv.explanation(ctx, respParams)
+}
+
+func (recv *LLM) explainCommitsRequest(
+	ctx context.Context,
+	commits iter.Seq2[string, error],
+	structured bool,
+	opts ...ExplainOpt,
+) (responses.ResponseNewParams, error) {
+	config := &explainConfig{}
+	for _, o := range opts {
+		if err := o(config); err != nil {
+			return responses.ResponseNewParams{}, err
+		}
+	}
+
+	instructionData := &explanationInstructionsTemplateData{
+		Language:   defaultLang.String(),
+		Structured: structured,
+		File:       config.file,
+		Lines:      config.lines,
+	}
+
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
+
+	instructions, err := execInstructionTmpl(
+		explainInstructions,
+		instructionData,
+	)
+	if err != nil {
+		return responses.ResponseNewParams{}, err
+	}
+
+	var explainInput responses.ResponseInputParam
+
+	explainInput = append(explainInput, gitDoContextMsg("commit"))
+
+	if recv.config.contextLoader != nil {
+		if msg, err := recv.retrieveContextTurn(); err == nil {
+			explainInput = append(explainInput, *msg)
+		}
+	}
+
+	var patches []string
+	for patch, err := range commits {
+		if err != nil {
+			return responses.ResponseNewParams{}, err
+		}
+
+		patches = append(patches, patch)
+	}
+
+	// a reduced commit keeps its message, which
+	// precedes the first hunk of its patch
+	patches, err = recv.fitBudget(ctx, patches)
+	if err != nil {
+		return responses.ResponseNewParams{}, err
+	}
+
+	for _, patch := range patches {
+		explainInput = append(explainInput, stringResponseItem(patch))
+	}
+
+	if len(config.issues) > 0 {
+		explainInput = append(explainInput, issuesResponseItem(config.issues))
+	}
+
+	explainInput = append(explainInput, stringResponseItem("GENERATE"))
+
+	return recv.newParams(instructions, explainInput), nil
+}
+
+// ExplainDiff of changes that are not described by a commit
+// message (e.g. staged changes). The source describes what
+// the diffs compare, such as "staged changes".
+func (recv *LLM) ExplainDiff(
+	ctx context.Context,
+	source string,
+	diffs iter.Seq2[string, error],
+	dst io.Writer,
+) error {
+	respParams, err := recv.explainDiffRequest(ctx, source, diffs, false)
+	if err != nil {
+		return err
+	}
+
+	return recv.stream(ctx, respParams, dst)
+}
+
+// ExplainDiffStructured explains the diffs as
+// structured output, rather than prose.
+func (recv *LLM) ExplainDiffStructured(
+	ctx context.Context,
+	source string,
+	diffs iter.Seq2[string, error],
+) (*Explanation, error) {
+	respParams, err := recv.explainDiffRequest(ctx, source, diffs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return recv.explanation(ctx, respParams)
+}
+
+func (recv *LLM) explainDiffRequest(
+	ctx context.Context,
+	source string,
+	diffs iter.Seq2[string, error],
+	structured bool,
+) (responses.ResponseNewParams, error) {
+	instructionData := &explanationInstructionsTemplateData{
+		Language:   defaultLang.String(),
+		Structured: structured,
+	}
+
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
+
+	instructions, err := execInstructionTmpl(
+		explainDiffInstructions,
+		instructionData,
+	)
+	if err != nil {
+		return responses.ResponseNewParams{}, err
+	}
+
+	var input responses.ResponseInputParam
+
+	input = append(input, gitDoContextMsg("explain"))
+
+	if recv.config.contextLoader != nil {
+		if msg, err := recv.retrieveContextTurn(); err == nil {
+			input = append(input, *msg)
+		}
+	}
+
+	if len(source) > 0 {
+		input = append(input,
+			stringResponseItem(fmt.Sprintf("SOURCE\n%s", source)),
+		)
+	}
+
+	var patches []string
+	for patch, err := range diffs {
+		if err != nil {
+			return responses.ResponseNewParams{}, err
+		}
+
+		patches = append(patches, patch)
+	}
+
+	patches, err = recv.fitBudget(ctx, patches)
+	if err != nil {
+		return responses.ResponseNewParams{}, err
+	}
+
+	for _, patch := range patches {
+		input = append(input, stringResponseItem(patch))
+	}
+
+	input = append(input, stringResponseItem("GENERATE"))
+
+	return recv.newParams(instructions, input), nil
+}
+
+// explanation requested by the params, as structured output.
+func (recv *LLM) explanation(
+	ctx context.Context,
+	respParams responses.ResponseNewParams,
+) (*Explanation, error) {
+	output := &Explanation{}
+	if err := recv.completeJSON(
+		ctx, respParams,
+		"explanation", explanationSchema,
+		output,
+		true,
+	); err != nil {
+		return nil, err
+	}
+
+	if len(output.Summary) == 0 {
+		return nil, ErrMalformedOutput
+	}
+
+	return output, nil
+}
+
+func (recv *LLM) GenerateCommit(
+	ctx context.Context,
+	commits iter.Seq2[string, error],
+	opts ...CommitOpt,
+) (string, error) {
+	respParams, config, err := recv.commitRequest(ctx, commits, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := recv.complete(ctx, respParams, !config.refresh)
+	if err != nil {
+		return "", err
+	}
+
+	rules := recv.formatRules()
+	for attempt := 0; ; attempt++ {
+		violations := rules.validate(output, config)
+		if len(violations) == 0 {
+			break
+		}
+
+		log.Debug().
+			Int("attempt", attempt).
+			Strs("violations", violations).
+			Msg("generated commit message is invalid")
+
+		if attempt >= recv.config.validationRetries {
+			// we have done what we can. The message is still
+			// likely useful, so it is up to the user from here.
+			break
+		}
+
+		// let the model see what it produced, and what was wrong with it
+		respParams.Input.OfInputItemList = append(
+			respParams.Input.OfInputItemList,
+			assistantResponseItem(output),
+			stringResponseItem(fmt.Sprintf("VIOLATIONS\n- %s",
+				strings.Join(violations, "\n- "),
+			)),
+			stringResponseItem("GENERATE"),
+		)
+
+		output, err = recv.complete(ctx, respParams, !config.refresh)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return output, nil
+}
+
+// GenerateCommitCandidates produces n alternative commit messages
+// from a single request, so the changes are only sent once.
+//
+// Candidates that do not pass validation are dropped,
+// unless none of them do.
+func (recv *LLM) GenerateCommitCandidates(
+	ctx context.Context,
+	commits iter.Seq2[string, error],
+	n int,
+	opts ...CommitOpt,
+) ([]string, error) {
+	respParams, config, err := recv.commitRequest(
+		ctx, commits,
+		append(opts, commitWithCandidates(n))...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Candidates []string `json:"candidates"`
+	}
+	if err := recv.completeJSON(
+		ctx, respParams,
+		"commit_candidates", candidatesSchema,
+		&output,
+		!config.refresh,
+	); err != nil {
+		return nil, err
+	}
+
+	if len(output.Candidates) == 0 {
+		return nil, ErrNoCandidates
+	}
+
+	rules := recv.formatRules()
+	valid := slices.DeleteFunc(
+		slices.Clone(output.Candidates),
+		func(c string) bool {
+			return len(rules.validate(c, config)) > 0
+		},
+	)
+	if len(valid) > 0 {
+		output.Candidates = valid
+	}
+
+	if len(output.Candidates) > n {
+		output.Candidates = output.Candidates[:n]
+	}
+
+	return output.Candidates, nil
+}
+
+// GroupChanges of the provided hunks into atomic commits.
+//
+// Hunks are identified by their position in the slice. Every hunk
+// is guaranteed to be included in exactly one of the returned groups.
+func (recv *LLM) GroupChanges(
+	ctx context.Context,
+	hunks []string,
+) ([]ChangeGroup, error) {
+	if len(hunks) == 0 {
+		return nil, ErrNoPatches
+	}
+
+	instructionData := &splitInstructionsTemplateData{
+		Language: defaultLang.String(),
+	}
+
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
+
+	instructions, err := execInstructionTmpl(
+		splitInstructions,
+		instructionData,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var input responses.ResponseInputParam
+
+	input = append(input, gitDoContextMsg("split"))
+
+	if recv.config.contextLoader != nil {
+		if msg, err := recv.retrieveContextTurn(); err == nil {
+			input = append(input, *msg)
+		}
+	}
+
+	// reducing a hunk keeps its position,
+	// so the groups still refer to it by index
+	fitted, err := recv.fitBudget(ctx, hunks)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, h := range fitted {
+		input = append(input, stringResponseItem(
+			fmt.Sprintf("HUNK %d\n%s", i, h),
+		))
+	}
+
+	input = append(input, stringResponseItem("GENERATE"))
+
+	var output struct {
+		Groups []ChangeGroup `json:"groups"`
+	}
+	if err := recv.completeJSON(
+		ctx, recv.newParams(instructions, input),
+		"change_groups", changeGroupsSchema,
+		&output,
+		true,
+	); err != nil {
+		return nil, err
+	}
+
+	return normalizeGroups(output.Groups, len(hunks)), nil
+}
+
+// CategorizeCommits by assigning each of the commit messages
+// one of the categories (e.g. the sections of a changelog).
+//
+// Commits are identified by their position in the slice, and
+// the category of each is returned in the same position. A
+// commit the model did not categorize has an empty category.
+func (recv *LLM) CategorizeCommits(
+	ctx context.Context,
+	messages []string,
+	categories []string,
+) ([]string, error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	instructions, err := execInstructionTmpl(
+		categorizeInstructions,
+		&categorizeInstructionsTemplateData{
+			Categories: categories,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var input responses.ResponseInputParam
+
+	input = append(input, gitDoContextMsg("changelog"))
+
+	if recv.config.contextLoader != nil {
+		if msg, err := recv.retrieveContextTurn(); err == nil {
+			input = append(input, *msg)
+		}
+	}
+
+	for i, m := range messages {
+		input = append(input, stringResponseItem(
+			fmt.Sprintf("COMMIT %d\n%s", i, m),
+		))
+	}
+
+	input = append(input, stringResponseItem("GENERATE"))
+
+	var output struct {
+		Commits []struct {
+			Commit   int    `json:"commit"`
+			Category string `json:"category"`
+		} `json:"commits"`
+	}
+	if err := recv.completeJSON(
+		ctx, recv.newParams(instructions, input),
+		"commit_categories", categoriesSchema(categories),
+		&output,
+		true,
+	); err != nil {
+		return nil, err
+	}
+
+	returner := make([]string, len(messages))
+	for _, c := range output.Commits {
+		if c.Commit < 0 || c.Commit >= len(messages) ||
+			!slices.Contains(categories, c.Category) {
+			continue
+		}
+
+		returner[c.Commit] = c.Category
+	}
+
+	return returner, nil
+}
+
+// SummarizeRelease of the version as the message of its
+// annotated tag, from the messages of its commits.
+func (recv *LLM) SummarizeRelease(
+	ctx context.Context,
+	version string,
+	messages iter.Seq2[string, error],
+) (string, error) {
+	instructionData := &releaseInstructionsTemplateData{
+		Language: defaultLang.String(),
+	}
+
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
+
+	instructions, err := execInstructionTmpl(
+		releaseInstructions,
+		instructionData,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var input responses.ResponseInputParam
+
+	input = append(input, gitDoContextMsg("release"))
+
+	if recv.config.contextLoader != nil {
+		if msg, err := recv.retrieveContextTurn(); err == nil {
+			input = append(input, *msg)
+		}
+	}
+
+	input = append(input,
+		stringResponseItem(fmt.Sprintf("VERSION\n%s", version)),
+	)
+
+	for msg, err := range messages {
+		if err != nil {
+			return "", err
+		}
+
+		input = append(input, stringResponseItem(msg))
+	}
+
+	input = append(input, stringResponseItem("GENERATE"))
+
+	output, err := recv.complete(ctx, recv.newParams(instructions, input), true)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// DescribePullRequest of a branch with a title and body, from the
+// messages of its commits and its combined diffs. The body fills in
+// the headings of the repo's pull request template, if provided.
+func (recv *LLM) DescribePullRequest(
+	ctx context.Context,
+	messages iter.Seq2[string, error],
+	diffs iter.Seq2[string, error],
+	prTemplate string,
+) (*PullRequest, error) {
+	instructionData := &pullRequestInstructionsTemplateData{
+		Language: defaultLang.String(),
+	}
+
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
+
+	instructions, err := execInstructionTmpl(
+		pullRequestInstructions,
+		instructionData,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var input responses.ResponseInputParam
+
+	input = append(input, gitDoContextMsg("pr"))
+
+	if recv.config.contextLoader != nil {
+		if msg, err := recv.retrieveContextTurn(); err == nil {
+			input = append(input, *msg)
+		}
+	}
+
+	if len(prTemplate) > 0 {
+		input = append(input,
+			stringResponseItem(fmt.Sprintf("TEMPLATE\n%s", prTemplate)),
+		)
+	}
+
+	for msg, err := range messages {
+		if err != nil {
+			return nil, err
+		}
+
+		input = append(input, stringResponseItem(fmt.Sprintf("COMMIT\n%s", msg)))
+	}
+
+	var patches []string
+	for patch, err := range diffs {
+		if err != nil {
+			return nil, err
+		}
+
+		patches = append(patches, patch)
+	}
+
+	patches, err = recv.fitBudget(ctx, patches)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, patch := range patches {
+		input = append(input, stringResponseItem(patch))
+	}
+
+	input = append(input, stringResponseItem("GENERATE"))
+
+	output := &PullRequest{}
+	if err := recv.completeJSON(
+		ctx, recv.newParams(instructions, input),
+		"pull_request", pullRequestSchema,
+		output,
+		true,
+	); err != nil {
+		return nil, err
+	}
+
+	output.Title = strings.TrimSpace(output.Title)
+	output.Body = strings.TrimSpace(output.Body)
+	if len(output.Title) == 0 {
+		return nil, ErrMalformedOutput
+	}
+
+	return output, nil
+}
+
+func (recv *LLM) GetModel() string {
+	return recv.config.model
+}
+
+func (recv *LLM) GetAPIDomain() string {
+	return fmt.Sprintf("%s.%s",
+		recv.apiUrl.Domain,
+		recv.apiUrl.TLD,
+	)
+}
+
+func (recv *LLM) ExplainStatus(
 	ctx context.Context,
-	commits iter.Seq2[string, error],
+	statusOutput string,
+	statusChanges iter.Seq2[string, error],
 	dst io.Writer,
 ) error {
-	startTime := time.Now()
-
-	instructionData := &explanationInstructionsTemplateData{
+	instructionData := &statusInstructionsTemplateData{
+		Color:    true,
 		Language: defaultLang.String(),
 	}
 
@@ -153,93 +996,78 @@ func (recv *LLM) ExplainCommits(
 	}
 
 	instructions, err := execInstructionTmpl(
-		explainInstructions,
+		statusInstructions,
 		instructionData,
 	)
 	if err != nil {
 		return err
 	}
 
-	var (
-		tokensIn, tokensOut int64
-		explainInput        responses.ResponseInputParam
-	)
+	if recv.config.outputLang != nil {
+		instructionData.Language = recv.config.outputLang.String()
+	}
 
-	explainInput = append(explainInput, gitDoContextMsg("commit"))
+	var input responses.ResponseInputParam
+
+	input = append(input, gitDoContextMsg("status"))
 
 	if recv.config.contextLoader != nil {
 		if msg, err := recv.retrieveContextTurn(); err == nil {
-			explainInput = append(explainInput, *msg)
+			input = append(input, *msg)
 		}
 	}
 
-	for patch, err := range commits {
+	input = append(input,
+		stringResponseItem(fmt.Sprintf("STATUS\n%s", statusOutput)),
+	)
+
+	var patches []string
+	for patch, err := range statusChanges {
 		if err != nil {
 			return err
 		}
 
-		explainInput = append(explainInput, stringResponseItem(patch))
+		patches = append(patches, patch)
 	}
 
-	explainInput = append(explainInput, stringResponseItem("GENERATE"))
-
-	respParams := responses.ResponseNewParams{
-		Model:        recv.config.model,
-		Instructions: param.NewOpt(instructions),
-		Input: responses.ResponseNewParamsInputUnion{
-			OfInputItemList: explainInput,
-		},
+	patches, err = recv.fitBudget(ctx, patches)
+	if err != nil {
+		return err
 	}
 
-	if len(recv.config.reasoning) > 0 {
-		respParams.Reasoning = shared.ReasoningParam{
-			Effort: shared.ReasoningEffort(recv.config.reasoning),
-		}
+	for _, patch := range patches {
+		input = append(input, stringResponseItem(patch))
 	}
 
-	stream := recv.client.Responses.NewStreaming(
-		ctx, respParams,
-	)
-	for stream.Next() {
-		cur := stream.Current()
-		if _, err := dst.Write([]byte(cur.Delta)); err != nil {
-			return err
-		}
+	input = append(input, stringResponseItem("GENERATE"))
 
-		tokensIn += cur.Response.Usage.InputTokens
-		tokensOut += cur.Response.Usage.OutputTokens
-	}
+	respParams := recv.newParams(instructions, input)
 
-	if err := stream.Err(); err != nil {
+	if err := recv.stream(ctx, respParams, dst); err != nil {
 		return err
 	}
 
-	log.Debug().
-		Int64("input_tokens", tokensIn).
-		Int64("output_tokens", tokensOut).
-		Stringer("latency", time.Since(startTime)).
-		Msg("llm response")
+	_, err = dst.Write([]byte("\n"))
 
-	return nil
+	return err
 }
 
-func (recv *LLM) GenerateCommit(
+func (recv *LLM) commitRequest(
 	ctx context.Context,
 	commits iter.Seq2[string, error],
 	opts ...CommitOpt,
-) (string, error) {
+) (responses.ResponseNewParams, *commitConfig, error) {
 	config := &commitConfig{}
 	for _, o := range opts {
 		if err := o(config); err != nil {
-			return "", err
+			return responses.ResponseNewParams{}, nil, err
 		}
 	}
 
-	startTime := time.Now()
-
 	instructionData := &commitInstructionsTemplateData{
 		Language: defaultLang.String(),
 		Format:   defaultCommitFormat,
+		Scopes:   recv.config.allowedScopes,
 	}
 
 	if recv.config.outputLang != nil {
@@ -250,17 +1078,29 @@ func (recv *LLM) GenerateCommit(
 		instructionData.Format = string(recv.config.commitFormat)
 	}
 
+	if recv.config.customFormat != nil {
+		custom, err := recv.config.customFormat.templateData(
+			instructionData.Language,
+		)
+		if err != nil {
+			return responses.ResponseNewParams{}, nil, err
+		}
+
+		instructionData.Format = custom.Name
+		instructionData.Custom = custom
+	}
+
 	instructions, err := execInstructionTmpl(
 		genCommitInstructions,
 		instructionData,
 	)
 	if err != nil {
-		return "", err
+		return responses.ResponseNewParams{}, nil, err
 	}
 
 	var (
-		tokensIn, tokensOut, patchCount int64
-		commitInput                     responses.ResponseInputParam
+		patches     []string
+		commitInput responses.ResponseInputParam
 	)
 
 	commitInput = append(commitInput, gitDoContextMsg("explain"))
@@ -272,17 +1112,30 @@ func (recv *LLM) GenerateCommit(
 	}
 
 	for patch, err := range commits {
-		patchCount++
-
 		if err != nil {
-			return "", err
+			return responses.ResponseNewParams{}, nil, err
 		}
 
-		commitInput = append(commitInput, stringResponseItem(patch))
+		patches = append(patches, patch)
+	}
+
+	if len(patches) == 0 {
+		return responses.ResponseNewParams{}, nil, ErrNoPatches
+	}
+
+	patches, err = recv.fitBudget(ctx, patches)
+	if err != nil {
+		return responses.ResponseNewParams{}, nil, err
+	}
+
+	if len(config.examples) > 0 {
+		msg := fmt.Sprintf("EXAMPLES\n%s",
+			strings.Join(config.examples, "\n"+examplesSeparator+"\n"))
+		commitInput = append(commitInput, stringResponseItem(msg))
 	}
 
-	if patchCount == 0 {
-		return "", ErrNoPatches
+	for _, patch := range patches {
+		commitInput = append(commitInput, stringResponseItem(patch))
 	}
 
 	if len(config.resolutions) > 0 {
@@ -291,18 +1144,49 @@ func (recv *LLM) GenerateCommit(
 		commitInput = append(commitInput, stringResponseItem(msg))
 	}
 
+	if len(config.issues) > 0 {
+		commitInput = append(commitInput, issuesResponseItem(config.issues))
+	}
+
+	if config.scoped {
+		scope := config.scope
+		if len(scope) == 0 {
+			scope = omitScope
+		}
+
+		commitInput = append(commitInput, stringResponseItem("SCOPE\n"+scope))
+	}
+
+	if len(config.breaking) > 0 {
+		msg := fmt.Sprintf("BREAKING\n- %s",
+			strings.Join(config.breaking, "\n- "))
+		commitInput = append(commitInput, stringResponseItem(msg))
+	}
+
 	if len(config.instructions) > 0 {
 		msg := fmt.Sprintf("INSTRUCTIONS\n%s", config.instructions)
 		commitInput = append(commitInput, stringResponseItem(msg))
 	}
 
+	if config.candidates > 1 {
+		msg := fmt.Sprintf("CANDIDATES\n%d", config.candidates)
+		commitInput = append(commitInput, stringResponseItem(msg))
+	}
+
 	commitInput = append(commitInput, stringResponseItem("GENERATE"))
 
+	return recv.newParams(instructions, commitInput), config, nil
+}
+
+func (recv *LLM) newParams(
+	instructions string,
+	input responses.ResponseInputParam,
+) responses.ResponseNewParams {
 	respParams := responses.ResponseNewParams{
 		Model:        recv.config.model,
 		Instructions: param.NewOpt(instructions),
 		Input: responses.ResponseNewParamsInputUnion{
-			OfInputItemList: commitInput,
+			OfInputItemList: input,
 		},
 	}
 
@@ -312,6 +1196,27 @@ func (recv *LLM) GenerateCommit(
 		}
 	}
 
+	return respParams
+}
+
+// complete the request, returning the entire output text.
+//
+// Unless lookup is false, a cached response to an identical
+// request is returned in place of making the request.
+func (recv *LLM) complete(
+	ctx context.Context,
+	respParams responses.ResponseNewParams,
+	lookup bool,
+) (string, error) {
+	key := recv.cacheKey(respParams)
+	if lookup {
+		if cached, found := recv.cached(key); found {
+			return cached, nil
+		}
+	}
+
+	startTime := time.Now()
+
 	resp, err := recv.client.Responses.New(
 		ctx, respParams,
 	)
@@ -319,100 +1224,38 @@ func (recv *LLM) GenerateCommit(
 		return "", err
 	}
 
-	tokensIn += resp.Usage.InputTokens
-	tokensOut += resp.Usage.OutputTokens
-	output := resp.OutputText()
-
 	log.Debug().
-		Int64("input_tokens", tokensIn).
-		Int64("output_tokens", tokensOut).
+		Int64("input_tokens", resp.Usage.InputTokens).
+		Int64("output_tokens", resp.Usage.OutputTokens).
 		Stringer("latency", time.Since(startTime)).
 		Msg("llm response")
 
-	return output, nil
-}
-
-func (recv *LLM) GetModel() string {
-	return recv.config.model
-}
+	output := resp.OutputText()
+	recv.store(key, output)
 
-func (recv *LLM) GetAPIDomain() string {
-	return fmt.Sprintf("%s.%s",
-		recv.apiUrl.Domain,
-		recv.apiUrl.TLD,
-	)
+	return output, nil
 }
 
-func (recv *LLM) ExplainStatus(
+// stream the request's output text to dst as it is generated.
+// A cached response to an identical request is written at once.
+func (recv *LLM) stream(
 	ctx context.Context,
-	statusOutput string,
-	statusChanges iter.Seq2[string, error],
+	respParams responses.ResponseNewParams,
 	dst io.Writer,
 ) error {
-	startTime := time.Now()
-
-	instructionData := &statusInstructionsTemplateData{
-		Color:    true,
-		Language: defaultLang.String(),
-	}
-
-	if recv.config.outputLang != nil {
-		instructionData.Language = recv.config.outputLang.String()
-	}
+	key := recv.cacheKey(respParams)
+	if cached, found := recv.cached(key); found {
+		_, err := io.WriteString(dst, cached)
 
-	instructions, err := execInstructionTmpl(
-		statusInstructions,
-		instructionData,
-	)
-	if err != nil {
 		return err
 	}
 
-	if recv.config.outputLang != nil {
-		instructionData.Language = recv.config.outputLang.String()
-	}
-
 	var (
+		startTime           = time.Now()
 		tokensIn, tokensOut int64
-		input               responses.ResponseInputParam
-	)
-
-	input = append(input, gitDoContextMsg("status"))
-
-	if recv.config.contextLoader != nil {
-		if msg, err := recv.retrieveContextTurn(); err == nil {
-			input = append(input, *msg)
-		}
-	}
-
-	input = append(input,
-		stringResponseItem(fmt.Sprintf("STATUS\n%s", statusOutput)),
+		output              strings.Builder
 	)
 
-	for patch, err := range statusChanges {
-		if err != nil {
-			return err
-		}
-
-		input = append(input, stringResponseItem(patch))
-	}
-
-	input = append(input, stringResponseItem("GENERATE"))
-
-	respParams := responses.ResponseNewParams{
-		Model:        recv.config.model,
-		Instructions: param.NewOpt(instructions),
-		Input: responses.ResponseNewParamsInputUnion{
-			OfInputItemList: input,
-		},
-	}
-
-	if len(recv.config.reasoning) > 0 {
-		respParams.Reasoning = shared.ReasoningParam{
-			Effort: shared.ReasoningEffort(recv.config.reasoning),
-		}
-	}
-
 	stream := recv.client.Responses.NewStreaming(
 		ctx, respParams,
 	)
@@ -422,6 +1265,7 @@ func (recv *LLM) ExplainStatus(
 			return err
 		}
 
+		output.WriteString(cur.Delta)
 		tokensIn += cur.Response.Usage.InputTokens
 		tokensOut += cur.Response.Usage.OutputTokens
 	}
@@ -430,19 +1274,117 @@ func (recv *LLM) ExplainStatus(
 		return err
 	}
 
-	if _, err := dst.Write([]byte("\n")); err != nil {
-		return err
-	}
-
 	log.Debug().
 		Int64("input_tokens", tokensIn).
 		Int64("output_tokens", tokensOut).
 		Stringer("latency", time.Since(startTime)).
 		Msg("llm response")
 
+	recv.store(key, output.String())
+
+	return nil
+}
+
+// cacheKey of the request. The params include the model, the
+// rendered instructions and every input, including the context
+// and patches, so any change to them produces a new key.
+func (recv *LLM) cacheKey(respParams responses.ResponseNewParams) string {
+	if recv.config.cache == nil {
+		return ""
+	}
+
+	params, err := json.Marshal(respParams)
+	if err != nil {
+		return ""
+	}
+
+	return cache.Key([]byte(recv.config.apiBase), params)
+}
+
+func (recv *LLM) cached(key string) (string, bool) {
+	if recv.config.cache == nil || len(key) == 0 {
+		return "", false
+	}
+
+	content, found := recv.config.cache.Get(key)
+
+	return string(content), found
+}
+
+func (recv *LLM) store(key, output string) {
+	if recv.config.cache == nil || len(key) == 0 || len(output) == 0 {
+		return
+	}
+
+	if err := recv.config.cache.Put(key, []byte(output)); err != nil {
+		// the response is still good, it just won't be reused
+		log.Debug().
+			Err(err).
+			Msg("unable to cache llm response")
+	}
+}
+
+// completeJSON completes the request using structured output
+// conforming to the provided schema. The output is decoded into dst.
+func (recv *LLM) completeJSON(
+	ctx context.Context,
+	respParams responses.ResponseNewParams,
+	name string,
+	schema map[string]any,
+	dst any,
+	lookup bool,
+) error {
+	respParams.Text = responses.ResponseTextConfigParam{
+		Format: responses.ResponseFormatTextConfigUnionParam{
+			OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+				Name:   name,
+				Schema: schema,
+				Strict: param.NewOpt(true),
+			},
+		},
+	}
+
+	output, err := recv.complete(ctx, respParams, lookup)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(output), dst); err != nil {
+		return errors.Join(ErrMalformedOutput, err)
+	}
+
 	return nil
 }
 
+// categoriesSchema of the output categorizing commits
+// into one of the categories.
+func categoriesSchema(categories []string) map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"commits": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"commit": map[string]any{
+							"type": "integer",
+						},
+						"category": map[string]any{
+							"type": "string",
+							"enum": categories,
+						},
+					},
+					"required":             []string{"commit", "category"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"commits"},
+		"additionalProperties": false,
+	}
+}
+
 func (recv *LLM) retrieveContextTurn() (*responses.ResponseInputItemUnionParam, error) {
 	rc, err := recv.config.contextLoader.LoadContextFile()
 	if err != nil {
@@ -468,6 +1410,54 @@ func (recv *LLM) retrieveContextTurn() (*responses.ResponseInputItemUnionParam,
 	}, nil
 }
 
+// normalizeGroups ensures each of the hunkCount hunks belongs to exactly
+// one group. Unknown and repeated hunks are dropped, and any hunks the
+// model did not assign are collected into a final group.
+func normalizeGroups(groups []ChangeGroup, hunkCount int) []ChangeGroup {
+	var (
+		seen       = make([]bool, hunkCount)
+		normalized = make([]ChangeGroup, 0, len(groups)+1)
+	)
+
+	for _, g := range groups {
+		var hunks []int
+		for _, h := range g.Hunks {
+			if h < 0 || h >= hunkCount || seen[h] {
+				continue
+			}
+
+			seen[h] = true
+			hunks = append(hunks, h)
+		}
+
+		if len(hunks) == 0 {
+			continue
+		}
+
+		slices.Sort(hunks)
+		normalized = append(normalized, ChangeGroup{
+			Summary: g.Summary,
+			Hunks:   hunks,
+		})
+	}
+
+	var remaining []int
+	for h, s := range seen {
+		if !s {
+			remaining = append(remaining, h)
+		}
+	}
+
+	if len(remaining) > 0 {
+		normalized = append(normalized, ChangeGroup{
+			Summary: "Remaining changes",
+			Hunks:   remaining,
+		})
+	}
+
+	return normalized
+}
+
 func execInstructionTmpl(t *template.Template, data any) (string, error) {
 	dst := &bytes.Buffer{}
 	if err := t.Execute(dst, data); err != nil {
@@ -491,6 +1481,17 @@ func gitDoContextMsg(subcommand string) responses.ResponseInputItemUnionParam {
 	}
 }
 
+func assistantResponseItem(str string) responses.ResponseInputItemUnionParam {
+	return responses.ResponseInputItemUnionParam{
+		OfMessage: &responses.EasyInputMessageParam{
+			Role: responses.EasyInputMessageRoleAssistant,
+			Content: responses.EasyInputMessageContentUnionParam{
+				OfString: param.NewOpt(str),
+			},
+		},
+	}
+}
+
 func stringResponseItem(str string) responses.ResponseInputItemUnionParam {
 	return responses.ResponseInputItemUnionParam{
 		OfMessage: &responses.EasyInputMessageParam{
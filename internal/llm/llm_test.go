@@ -3,11 +3,15 @@ package llm_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"iter"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/julianwyz/git-do/internal/cache"
 	"github.com/julianwyz/git-do/internal/git"
 	"github.com/julianwyz/git-do/internal/llm"
 	"golang.org/x/text/language"
@@ -15,7 +19,12 @@ import (
 
 type (
 	ctxLoader struct{}
-	roundtrip struct{}
+	roundtrip struct {
+		output string
+		// outputs are responded with in order, before output
+		outputs  []string
+		requests []string
+	}
 )
 
 // Some of these tests are kinda shallow right now
@@ -72,6 +81,257 @@ func TestExplainCommits(t *testing.T) {
 	}
 }
 
+func TestExplainCommits__File(t *testing.T) {
+	transport := &roundtrip{}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.ExplainCommits(
+		t.Context(),
+		commitList("commit abc123\n\n    Add greeting"),
+		&bytes.Buffer{},
+		llm.ExplainWithFile("cmd/main.go", "10,20"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	req := transport.requests[0]
+	if !strings.Contains(req, "history of the file `cmd/main.go`") ||
+		!strings.Contains(req, "its lines `10,20`") {
+		t.Fatal("expected the instructions to explain the file's history")
+	}
+}
+
+func TestExplainCommits__Budget(t *testing.T) {
+	transport := &roundtrip{}
+	client, err := llm.New(
+		llm.WithTokenBudget(100, llm.BudgetStrategyStat),
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := "commit abc123\n\n    Fix small\n\ndiff --git a/small.txt b/small.txt\n@@ -1 +1 @@\n-small before\n+small after\n"
+	large := "commit def456\n\n    Add large\n\ndiff --git a/large.txt b/large.txt\n@@ -1,500 +1,500 @@\n" +
+		strings.Repeat("+large addition\n", 500)
+
+	if err := client.ExplainCommits(
+		t.Context(),
+		commitList(small, large),
+		&bytes.Buffer{},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	req := transport.requests[0]
+	if !strings.Contains(req, "small after") {
+		t.Fatal("expected small commit to be kept whole")
+	}
+
+	if strings.Contains(req, "large addition") {
+		t.Fatal("expected large commit to be reduced")
+	}
+
+	if !strings.Contains(req, "Add large") ||
+		!strings.Contains(req, "500 insertions(+)") {
+		t.Fatal("expected large commit to be reduced to its message and a stat")
+	}
+}
+
+func TestExplainCommitsStructured(t *testing.T) {
+	transport := &roundtrip{
+		output: `{"summary":"Adds greetings.","commits":[{"hash":"abc123","summary":"Adds a greeting."}],` +
+			`"areas":["cli"],"user_facing_changes":["Greets the user."],"risks":[],"issues":["#1"]}`,
+	}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := client.ExplainCommitsStructured(
+		t.Context(),
+		commitList("commit abc123\n\n    Add greeting"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if explanation.Summary != "Adds greetings." ||
+		len(explanation.Commits) != 1 ||
+		explanation.Commits[0].Hash != "abc123" ||
+		!slices.Equal(explanation.Issues, []string{"#1"}) {
+		t.Fatalf("unexpected explanation: %+v", explanation)
+	}
+
+	req := transport.requests[0]
+	if !strings.Contains(req, `"json_schema"`) || !strings.Contains(req, `"user_facing_changes"`) {
+		t.Fatal("expected the request to use structured output")
+	}
+
+	transport.output = `{"summary":""}`
+	if _, err := client.ExplainCommitsStructured(
+		t.Context(),
+		commitList("commit def456"),
+	); !errors.Is(err, llm.ErrMalformedOutput) {
+		t.Fatalf("expected malformed output, got %v", err)
+	}
+}
+
+func TestCategorizeCommits(t *testing.T) {
+	transport := &roundtrip{
+		output: `{"commits":[{"commit":1,"category":"Fixed"},{"commit":0,"category":"Added"},{"commit":5,"category":"Added"}]}`,
+	}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories, err := client.CategorizeCommits(
+		t.Context(),
+		[]string{"Add foo", "Fix bar", "Tidy up"},
+		[]string{"Added", "Fixed", "Omitted"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(categories, []string{"Added", "Fixed", ""}) {
+		t.Fatalf("unexpected categories: %v", categories)
+	}
+
+	if req := transport.requests[0]; !strings.Contains(req, `COMMIT 1\nFix bar`) {
+		t.Fatal("expected the commits to be numbered")
+	}
+}
+
+func TestSummarizeRelease(t *testing.T) {
+	transport := &roundtrip{
+		output: "v1.1.0: Greetings\n\nAdds a greeting.\n",
+	}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := client.SummarizeRelease(
+		t.Context(),
+		"v1.1.0",
+		commitList("feat: add greeting"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary != "v1.1.0: Greetings\n\nAdds a greeting." {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+
+	if req := transport.requests[0]; !strings.Contains(req, `VERSION\nv1.1.0`) {
+		t.Fatal("expected the version to be included in the request")
+	}
+}
+
+func TestDescribePullRequest(t *testing.T) {
+	transport := &roundtrip{
+		output: `{"title":"Add greetings","body":"## Summary\n\nGreets the user.\n"}`,
+	}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := client.DescribePullRequest(
+		t.Context(),
+		commitList("feat: add greeting"),
+		commitList("diff --git a/main.go b/main.go"),
+		"## Summary\n\n## Testing\n",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.Title != "Add greetings" || pr.Body != "## Summary\n\nGreets the user." {
+		t.Fatalf("unexpected pull request: %+v", pr)
+	}
+
+	req := transport.requests[0]
+	for _, expected := range []string{
+		`TEMPLATE\n## Summary\n\n## Testing`,
+		`COMMIT\nfeat: add greeting`,
+		`"pull_request"`,
+	} {
+		if !strings.Contains(req, expected) {
+			t.Fatalf("expected the request to contain %q", expected)
+		}
+	}
+
+	transport.output = `{"title":" ","body":""}`
+	if _, err := client.DescribePullRequest(
+		t.Context(),
+		commitList("fix: typo"),
+		commitList("diff --git a/main.go b/main.go"),
+		"",
+	); !errors.Is(err, llm.ErrMalformedOutput) {
+		t.Fatalf("expected malformed output, got %v", err)
+	}
+}
+
+func TestExplainDiff(t *testing.T) {
+	transport := &roundtrip{}
+	client, err := llm.New(
+		llm.WithOutputLanguage(language.AmericanEnglish),
+		llm.WithContextLoader(&ctxLoader{}),
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := &bytes.Buffer{}
+	if err := client.ExplainDiff(
+		t.Context(),
+		"staged changes",
+		commitList("hello world"),
+		dst,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if req := transport.requests[0]; !strings.Contains(
+		req,
+		`SOURCE\nstaged changes`,
+	) {
+		t.Fatal("expected the source to be included in the request")
+	}
+}
+
 func TestGenerateCommit(t *testing.T) {
 	client, err := llm.New(
 		llm.WithOutputLanguage(language.AmericanEnglish),
@@ -94,6 +354,542 @@ func TestGenerateCommit(t *testing.T) {
 	}
 }
 
+func TestGenerateCommitCandidates(t *testing.T) {
+	client, err := llm.New(
+		llm.WithOutputLanguage(language.AmericanEnglish),
+		llm.WithCommitFormat(git.CommitFormatGithub),
+		llm.WithHTTPClient(makeOutputClient(
+			`{"candidates":["Add foo","Introduce foo","Create foo"]}`,
+		)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := client.GenerateCommitCandidates(
+		t.Context(),
+		commitList("hello world"),
+		2,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(candidates) != 2 {
+		t.Fatal("expected candidates to be limited")
+	}
+
+	if candidates[0] != "Add foo" {
+		t.Fatal("unexpected candidate")
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		client, err := llm.New(
+			llm.WithHTTPClient(makeOutputClient("Add foo")),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = client.GenerateCommitCandidates(
+			t.Context(),
+			commitList("hello world"),
+			2,
+		)
+		if !errors.Is(err, llm.ErrMalformedOutput) {
+			t.Fatal("expected malformed output error")
+		}
+	})
+}
+
+func TestGroupChanges(t *testing.T) {
+	client, err := llm.New(
+		llm.WithHTTPClient(makeOutputClient(
+			`{"groups":[{"summary":"First","hunks":[2,0,9]},{"summary":"Second","hunks":[0]}]}`,
+		)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := client.GroupChanges(
+		t.Context(),
+		[]string{"a", "b", "c"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hunk 9 is unknown, hunk 0 is repeated and
+	// hunk 1 was never assigned
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	if !slices.Equal(groups[0].Hunks, []int{0, 2}) {
+		t.Fatal("unexpected first group")
+	}
+
+	if !slices.Equal(groups[1].Hunks, []int{1}) {
+		t.Fatal("expected unassigned hunks to be grouped")
+	}
+}
+
+func TestGroupChanges__Budget(t *testing.T) {
+	transport := &roundtrip{
+		output: `{"groups":[{"summary":"First","hunks":[0,1]}]}`,
+	}
+	client, err := llm.New(
+		llm.WithTokenBudget(100, llm.BudgetStrategyStat),
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := "diff --git a/small.txt b/small.txt\n@@ -1 +1 @@\n-small before\n+small after\n"
+	large := "diff --git a/large.txt b/large.txt\n@@ -1,500 +1,500 @@\n" +
+		strings.Repeat("+large addition\n", 500)
+
+	groups, err := client.GroupChanges(
+		t.Context(),
+		[]string{small, large},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 1 || !slices.Equal(groups[0].Hunks, []int{0, 1}) {
+		t.Fatal("expected the hunks to be grouped by index")
+	}
+
+	req := transport.requests[0]
+	if !strings.Contains(req, "small after") {
+		t.Fatal("expected small hunk to be kept whole")
+	}
+
+	if strings.Contains(req, "large addition") {
+		t.Fatal("expected large hunk to be reduced")
+	}
+
+	if !strings.Contains(req, "HUNK 1") {
+		t.Fatal("expected reduced hunk to keep its index")
+	}
+}
+
+func TestGenerateCommit__Budget(t *testing.T) {
+	transport := &roundtrip{
+		output: "Add foo",
+	}
+	client, err := llm.New(
+		llm.WithTokenBudget(100, llm.BudgetStrategyStat),
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small := "diff --git a/small.txt b/small.txt\n@@ -1 +1 @@\n-small before\n+small after\n"
+	large := "diff --git a/large.txt b/large.txt\n@@ -1,500 +1,500 @@\n" +
+		strings.Repeat("+large addition\n", 500)
+
+	if _, err := client.GenerateCommit(
+		t.Context(),
+		commitList(small, large),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.requests) != 1 {
+		t.Fatal("expected a single request")
+	}
+
+	req := transport.requests[0]
+	if !strings.Contains(req, "small after") {
+		t.Fatal("expected small patch to be kept whole")
+	}
+
+	if strings.Contains(req, "large addition") {
+		t.Fatal("expected large patch to be reduced")
+	}
+
+	if !strings.Contains(req, "500 insertions(+)") {
+		t.Fatal("expected large patch to be reduced to a stat")
+	}
+}
+
+func TestGenerateCommit__CustomFormat(t *testing.T) {
+	transport := &roundtrip{
+		output: "cli: add foo",
+	}
+	client, err := llm.New(
+		llm.WithCustomFormat(llm.FormatSpec{
+			Name:           "kernel",
+			Rules:          "- Write in {{ .Language }}.",
+			TitleMaxLength: 60,
+			Body:           llm.BodyRuleNone,
+			Trailers:       []string{"Change-Type"},
+		}),
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GenerateCommit(
+		t.Context(),
+		commitList("hello world"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	req := transport.requests[0]
+	for _, expected := range []string{
+		"Output format (kernel)",
+		"Write in en-US.",
+		"Must be 60 characters or fewer",
+		"Do NOT include a commit body.",
+		"Change-Type: \u003cvalue\u003e",
+	} {
+		if !strings.Contains(req, expected) {
+			t.Fatalf("expected instructions to contain %q", expected)
+		}
+	}
+}
+
+func TestGenerateCommit__Examples(t *testing.T) {
+	transport := &roundtrip{
+		output: "Add foo",
+	}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GenerateCommit(
+		t.Context(),
+		commitList("hello world"),
+		llm.CommitWithExamples("PROJ-1: fix the thing", "PROJ-2: add the thing"),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if req := transport.requests[0]; !strings.Contains(
+		req,
+		`EXAMPLES\nPROJ-1: fix the thing\n---\nPROJ-2: add the thing`,
+	) {
+		t.Fatal("expected examples to be included in the request")
+	}
+}
+
+func TestGenerateCommit__Cache(t *testing.T) {
+	rc, err := cache.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &roundtrip{
+		outputs: []string{"Add foo", "Add bar"},
+	}
+	client, err := llm.New(
+		llm.WithCache(rc),
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		msg, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg != "Add foo" {
+			t.Fatalf("unexpected message: %s", msg)
+		}
+	}
+
+	if len(transport.requests) != 1 {
+		t.Fatalf("expected the identical request to be cached, got %d requests", len(transport.requests))
+	}
+
+	msg, err := client.GenerateCommit(
+		t.Context(),
+		commitList("hello world"),
+		llm.CommitWithRefresh(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg != "Add bar" || len(transport.requests) != 2 {
+		t.Fatal("expected a refresh to bypass the cache")
+	}
+}
+
+func TestGenerateCommit__Issues(t *testing.T) {
+	transport := &roundtrip{
+		output: "Add foo\n\nCloses: PROJ-1",
+	}
+	client, err := llm.New(
+		llm.WithHTTPClient(&http.Client{
+			Transport: transport,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GenerateCommit(
+		t.Context(),
+		commitList("hello world"),
+		llm.CommitWithResolutions("PROJ-1"),
+		llm.CommitWithIssues(llm.Issue{
+			Ref:         "PROJ-1",
+			Title:       "Foo is missing",
+			Description: "Users need foo.",
+		}),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if req := transport.requests[0]; !strings.Contains(
+		req,
+		`ISSUES\n---\nREF: PROJ-1\nTITLE: Foo is missing\nDESCRIPTION:\nUsers need foo.`,
+	) {
+		t.Fatal("expected issues to be included in the request")
+	}
+}
+
+func TestGenerateCommit__Validation(t *testing.T) {
+	t.Run("retry", func(t *testing.T) {
+		transport := &roundtrip{
+			outputs: []string{
+				"```\nAdd a title that is far too long to be used as a commit title\n```",
+			},
+			output: "Add foo\n\nExplain why foo was added.\n\nCloses: https://example.com/1",
+		}
+		client, err := llm.New(
+			llm.WithHTTPClient(&http.Client{
+				Transport: transport,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+			llm.CommitWithResolutions("https://example.com/1"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(msg, "Add foo") {
+			t.Fatal("expected regenerated message")
+		}
+
+		if len(transport.requests) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(transport.requests))
+		}
+
+		retry := transport.requests[1]
+		if !strings.Contains(retry, "VIOLATIONS") ||
+			!strings.Contains(retry, "code fences") ||
+			!strings.Contains(retry, "50 characters or fewer") {
+			t.Fatal("expected violations to be provided")
+		}
+	})
+
+	t.Run("conventional", func(t *testing.T) {
+		transport := &roundtrip{
+			outputs: []string{
+				"feature(cli): add foo",
+				"feat(): add foo",
+			},
+			output: "feat(cli): add foo",
+		}
+		client, err := llm.New(
+			llm.WithCommitFormat(git.CommitFormatConventional),
+			llm.WithValidationRetries(5),
+			llm.WithHTTPClient(&http.Client{
+				Transport: transport,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg != "feat(cli): add foo" || len(transport.requests) != 3 {
+			t.Fatal("expected invalid type and scope to be retried")
+		}
+
+		if !strings.Contains(transport.requests[1], `The type \"feature\" is not allowed`) {
+			t.Fatal("expected type violation")
+		}
+	})
+
+	t.Run("scope", func(t *testing.T) {
+		transport := &roundtrip{
+			outputs: []string{
+				"feat(CLI): add foo",
+				"feat(cli): add foo",
+			},
+			output: "feat: add foo",
+		}
+		client, err := llm.New(
+			llm.WithCommitFormat(git.CommitFormatConventional),
+			llm.WithAllowedScopes("cli", "git"),
+			llm.WithHTTPClient(&http.Client{
+				Transport: transport,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+			llm.CommitWithScope(""),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg != "feat: add foo" || len(transport.requests) != 3 {
+			t.Fatal("expected scopes to be retried")
+		}
+
+		if !strings.Contains(transport.requests[0], `SCOPE\nOMIT`) ||
+			!strings.Contains(transport.requests[0], `Allowed scopes (any other scope is invalid):\ncli, git`) {
+			t.Fatal("expected scope and allowed scopes to be included in the request")
+		}
+
+		if !strings.Contains(transport.requests[1], "must not include a scope") {
+			t.Fatal("expected scope violation")
+		}
+
+		// without a determined scope, only allowed scopes are valid
+		transport = &roundtrip{
+			outputs: []string{"feat(CLI): add foo"},
+			output:  "feat(cli): add foo",
+		}
+		client, err = llm.New(
+			llm.WithCommitFormat(git.CommitFormatConventional),
+			llm.WithAllowedScopes("cli", "git"),
+			llm.WithHTTPClient(&http.Client{
+				Transport: transport,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(transport.requests[1], `The scope \"CLI\" is not allowed`) {
+			t.Fatal("expected allowed scope violation")
+		}
+	})
+
+	t.Run("breaking", func(t *testing.T) {
+		transport := &roundtrip{
+			outputs: []string{
+				"feat(api): change foo",
+			},
+			output: "feat(api)!: change foo\n\nBREAKING CHANGE: Foo requires a second argument.",
+		}
+		client, err := llm.New(
+			llm.WithCommitFormat(git.CommitFormatConventional),
+			llm.WithHTTPClient(&http.Client{
+				Transport: transport,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+			llm.CommitWithBreakingChanges("foo: changed func Foo from `func(int)` to `func(int, int)`"),
+		); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transport.requests) != 2 {
+			t.Fatalf("expected 2 requests, got %d", len(transport.requests))
+		}
+
+		if !strings.Contains(transport.requests[0], "BREAKING\\n- foo: changed func Foo") {
+			t.Fatal("expected breaking changes to be included in the request")
+		}
+
+		if !strings.Contains(transport.requests[1], "footer describing them") {
+			t.Fatal("expected missing footer violation")
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		transport := &roundtrip{
+			output: "Add foo\nwithout a blank line",
+		}
+		client, err := llm.New(
+			llm.WithValidationRetries(1),
+			llm.WithHTTPClient(&http.Client{
+				Transport: transport,
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := client.GenerateCommit(
+			t.Context(),
+			commitList("hello world"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg != "Add foo\nwithout a blank line" || len(transport.requests) != 2 {
+			t.Fatal("expected last message after retries are exhausted")
+		}
+	})
+}
+
 func TestExplainStatus(t *testing.T) {
 	client, err := llm.New(
 		llm.WithOutputLanguage(language.AmericanEnglish),
@@ -157,13 +953,49 @@ func makeClient() *http.Client {
 	}
 }
 
+// makeOutputClient responds to every request with
+// the provided output text.
+func makeOutputClient(output string) *http.Client {
+	return &http.Client{
+		Transport: &roundtrip{
+			output: output,
+		},
+	}
+}
+
 func (recv *roundtrip) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	if req.Body != nil {
+		reqBody, _ := io.ReadAll(req.Body)
+		recv.requests = append(recv.requests, string(reqBody))
+	}
+
 	hdr := make(http.Header)
 	hdr.Set("content-type", "application/json")
 
+	output := recv.output
+	if len(recv.outputs) > 0 {
+		output, recv.outputs = recv.outputs[0], recv.outputs[1:]
+	}
+
+	body := map[string]any{}
+	if len(output) > 0 {
+		body["output"] = []any{
+			map[string]any{
+				"type": "message",
+				"role": "assistant",
+				"content": []any{
+					map[string]any{
+						"type": "output_text",
+						"text": output,
+					},
+				},
+			},
+		}
+	}
+
 	res = &http.Response{
 		Header: hdr,
-		Body:   recv.makeBody(map[string]any{}),
+		Body:   recv.makeBody(body),
 	}
 
 	return
@@ -8,23 +8,48 @@ import (
 
 type (
 	llmConfig struct {
-		commitFormat  git.CommitFormat
-		outputLang    *language.Tag
-		apiBase       string
-		apiKey        string
-		model         string
-		reasoning     ReasoningLevel
-		contextLoader contextLoader
-		http          option.HTTPClient
+		commitFormat      git.CommitFormat
+		customFormat      *FormatSpec
+		outputLang        *language.Tag
+		apiBase           string
+		apiKey            string
+		model             string
+		reasoning         ReasoningLevel
+		budgetTokens      int
+		budgetStrategy    BudgetStrategy
+		validationRetries int
+		allowedScopes     []string
+		contextLoader     contextLoader
+		cache             responseCache
+		http              option.HTTPClient
 	}
 
 	commitConfig struct {
 		resolutions  []string
 		instructions string
+		candidates   int
+		examples     []string
+		issues       []Issue
+		breaking     []string
+		// scope of the commit, when scoped. An empty
+		// scope must be omitted from the message.
+		scope  string
+		scoped bool
+		// refresh the response, rather than reusing a cached one
+		refresh bool
 	}
 
-	LLMOpt    func(*llmConfig) error
-	CommitOpt func(*commitConfig) error
+	explainConfig struct {
+		issues []Issue
+		// file whose history the commits are.
+		file string
+		// lines of the file the history is limited to.
+		lines string
+	}
+
+	LLMOpt     func(*llmConfig) error
+	CommitOpt  func(*commitConfig) error
+	ExplainOpt func(*explainConfig) error
 )
 
 func CommitWithInstructions(i string) CommitOpt {
@@ -43,6 +68,85 @@ func CommitWithResolutions(rs ...string) CommitOpt {
 	}
 }
 
+// CommitWithExamples provides existing commit messages
+// whose style the generated message should match.
+func CommitWithExamples(msgs ...string) CommitOpt {
+	return func(cc *commitConfig) error {
+		cc.examples = append(cc.examples, msgs...)
+
+		return nil
+	}
+}
+
+// CommitWithIssues describes the issues resolved by the changes.
+func CommitWithIssues(issues ...Issue) CommitOpt {
+	return func(cc *commitConfig) error {
+		cc.issues = append(cc.issues, issues...)
+
+		return nil
+	}
+}
+
+// ExplainWithIssues describes the issues referenced by the commits.
+func ExplainWithIssues(issues ...Issue) ExplainOpt {
+	return func(ec *explainConfig) error {
+		ec.issues = append(ec.issues, issues...)
+
+		return nil
+	}
+}
+
+// ExplainWithFile explains the commits as the history of the
+// file, optionally limited to a range of its lines (e.g. "10,20"),
+// rather than as a set of changes.
+func ExplainWithFile(file, lines string) ExplainOpt {
+	return func(ec *explainConfig) error {
+		ec.file = file
+		ec.lines = lines
+
+		return nil
+	}
+}
+
+// CommitWithBreakingChanges describes changes that break the
+// public API, as detected by static analysis of the changes.
+func CommitWithBreakingChanges(breaks ...string) CommitOpt {
+	return func(cc *commitConfig) error {
+		cc.breaking = append(cc.breaking, breaks...)
+
+		return nil
+	}
+}
+
+// CommitWithScope of conventional commits, as determined by the
+// changed paths. An empty scope is omitted from the message.
+func CommitWithScope(scope string) CommitOpt {
+	return func(cc *commitConfig) error {
+		cc.scope = scope
+		cc.scoped = true
+
+		return nil
+	}
+}
+
+// CommitWithRefresh always requests a new message, even if a
+// response to an identical request is cached (e.g. regenerating).
+func CommitWithRefresh() CommitOpt {
+	return func(cc *commitConfig) error {
+		cc.refresh = true
+
+		return nil
+	}
+}
+
+func commitWithCandidates(n int) CommitOpt {
+	return func(cc *commitConfig) error {
+		cc.candidates = n
+
+		return nil
+	}
+}
+
 func WithHTTPClient(c option.HTTPClient) LLMOpt {
 	return func(lc *llmConfig) error {
 		lc.http = c
@@ -51,6 +155,15 @@ func WithHTTPClient(c option.HTTPClient) LLMOpt {
 	}
 }
 
+// WithCache of responses, reused for identical requests.
+func WithCache(c responseCache) LLMOpt {
+	return func(lc *llmConfig) error {
+		lc.cache = c
+
+		return nil
+	}
+}
+
 func WithOutputLanguage(l language.Tag) LLMOpt {
 	return func(lc *llmConfig) error {
 		lc.outputLang = &l
@@ -67,6 +180,16 @@ func WithCommitFormat(format git.CommitFormat) LLMOpt {
 	}
 }
 
+// WithCustomFormat uses a user-defined commit format
+// in place of the built-in formats.
+func WithCustomFormat(spec FormatSpec) LLMOpt {
+	return func(lc *llmConfig) error {
+		lc.customFormat = &spec
+
+		return nil
+	}
+}
+
 func WithContextLoader(l contextLoader) LLMOpt {
 	return func(lc *llmConfig) error {
 		lc.contextLoader = l
@@ -99,6 +222,38 @@ func WithModel(m string) LLMOpt {
 	}
 }
 
+// WithTokenBudget limits the approximate number of tokens used
+// by patches. Patches that exceed their share of the budget are
+// reduced using the provided strategy.
+func WithTokenBudget(tokens int, strategy BudgetStrategy) LLMOpt {
+	return func(lc *llmConfig) error {
+		lc.budgetTokens = tokens
+		lc.budgetStrategy = strategy
+
+		return nil
+	}
+}
+
+// WithAllowedScopes of conventional commits. Messages
+// with any other scope are rejected.
+func WithAllowedScopes(scopes ...string) LLMOpt {
+	return func(lc *llmConfig) error {
+		lc.allowedScopes = scopes
+
+		return nil
+	}
+}
+
+// WithValidationRetries sets how many times a generated commit
+// message that violates its format is regenerated.
+func WithValidationRetries(n int) LLMOpt {
+	return func(lc *llmConfig) error {
+		lc.validationRetries = n
+
+		return nil
+	}
+}
+
 func WithReasoningLevel(l ReasoningLevel) LLMOpt {
 	return func(lc *llmConfig) error {
 		lc.reasoning = l
@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/julianwyz/git-do/internal/git"
+)
+
+type (
+	// formatRules that a generated commit message
+	// is validated against.
+	formatRules struct {
+		titleMaxLength int
+		conventional   bool
+		scopes         []string
+		body           BodyRule
+		trailers       []string
+	}
+)
+
+const (
+	defaultValidationRetries = 2
+	bodyMaxLineLength        = 72
+	closesTrailer            = "Closes:"
+	breakingChangeFooter     = "BREAKING CHANGE:"
+)
+
+var (
+	conventionalTypes = []string{
+		"feat", "fix", "refactor", "perf", "docs",
+		"test", "chore", "build", "ci",
+	}
+	conventionalHeaderPattern = regexp.MustCompile(
+		`^([a-zA-Z]+)(\(([^()]*)\))?(!)?: \S`,
+	)
+	trailerPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*: `)
+)
+
+// formatRules of the configured commit format.
+func (recv *LLM) formatRules() formatRules {
+	if spec := recv.config.customFormat; spec != nil {
+		return formatRules{
+			titleMaxLength: spec.TitleMaxLength,
+			body:           spec.Body,
+			trailers:       spec.Trailers,
+		}
+	}
+
+	if recv.config.commitFormat == git.CommitFormatConventional {
+		return formatRules{
+			titleMaxLength: 72,
+			conventional:   true,
+			scopes:         recv.config.allowedScopes,
+		}
+	}
+
+	return formatRules{
+		titleMaxLength: 50,
+	}
+}
+
+// validate the message, returning a description of
+// each rule that was violated.
+func (recv formatRules) validate(msg string, config *commitConfig) []string {
+	var violations []string
+
+	msg = strings.TrimSpace(msg)
+	if len(msg) == 0 {
+		return []string{"The message is empty."}
+	}
+
+	if strings.Contains(msg, "```") {
+		violations = append(violations,
+			"The message must not be wrapped in, or contain, Markdown code fences.",
+		)
+	}
+
+	lines := strings.Split(msg, "\n")
+	title := lines[0]
+
+	if l := len([]rune(title)); recv.titleMaxLength > 0 && l > recv.titleMaxLength {
+		violations = append(violations, fmt.Sprintf(
+			"The title is %d characters long, it must be %d characters or fewer.",
+			l, recv.titleMaxLength,
+		))
+	}
+
+	if len(lines) > 1 && len(strings.TrimSpace(lines[1])) > 0 {
+		violations = append(violations,
+			"The title must be followed by a blank line.",
+		)
+	}
+
+	if recv.conventional {
+		violations = append(violations, validateConventionalHeader(title)...)
+		violations = append(violations, recv.validateScope(title, config)...)
+	}
+
+	var (
+		body    = lines[min(len(lines), 2):]
+		hasBody bool
+	)
+	for _, line := range body {
+		if trailerPattern.MatchString(line) || strings.HasPrefix(line, breakingChangeFooter) {
+			continue
+		}
+
+		if len(strings.TrimSpace(line)) > 0 {
+			hasBody = true
+		}
+
+		if l := len([]rune(line)); l > bodyMaxLineLength &&
+			// long, unbreakable content such as URLs can't be wrapped
+			strings.Contains(strings.TrimSpace(line), " ") &&
+			!strings.Contains(line, "://") {
+			violations = append(violations, fmt.Sprintf(
+				"The body line %q is %d characters long, body lines must be wrapped at %d characters.",
+				line, l, bodyMaxLineLength,
+			))
+		}
+	}
+
+	switch recv.body {
+	case BodyRuleRequired:
+		if !hasBody {
+			violations = append(violations, "A commit body is required.")
+		}
+	case BodyRuleNone:
+		if hasBody {
+			violations = append(violations, "The message must not include a commit body.")
+		}
+	}
+
+	for _, line := range body {
+		if after, found := strings.CutPrefix(line, closesTrailer); found &&
+			len(strings.TrimSpace(after)) == 0 {
+			violations = append(violations, fmt.Sprintf(
+				"The line %q must reference an issue.", line,
+			))
+		}
+	}
+
+	for _, r := range config.resolutions {
+		if !slices.Contains(body, closesTrailer+" "+r) {
+			violations = append(violations, fmt.Sprintf(
+				"The message must include the line %q.", closesTrailer+" "+r,
+			))
+		}
+	}
+
+	if recv.conventional && len(config.breaking) > 0 &&
+		!slices.ContainsFunc(body, func(line string) bool {
+			return strings.HasPrefix(line, breakingChangeFooter)
+		}) {
+		violations = append(violations, fmt.Sprintf(
+			"The changes break the public API, the message must include a %q footer describing them.",
+			breakingChangeFooter,
+		))
+	}
+
+	for _, t := range recv.trailers {
+		if !slices.ContainsFunc(body, func(line string) bool {
+			return strings.HasPrefix(line, t+": ")
+		}) {
+			violations = append(violations, fmt.Sprintf(
+				"The message must end with a %q trailer.", t,
+			))
+		}
+	}
+
+	return violations
+}
+
+// validateScope of a conventional title against the scope
+// determined for the changes, or the allowed scopes.
+func (recv formatRules) validateScope(title string, config *commitConfig) []string {
+	m := conventionalHeaderPattern.FindStringSubmatch(title)
+	if m == nil {
+		// already a violation of the header
+		return nil
+	}
+
+	scope, hasScope := strings.TrimSpace(m[3]), len(m[2]) > 0
+	switch {
+	case config.scoped && len(config.scope) == 0 && hasScope:
+		return []string{"The title must not include a scope."}
+	case config.scoped && len(config.scope) > 0 && scope != config.scope:
+		return []string{fmt.Sprintf("The scope must be %q.", config.scope)}
+	case hasScope && len(recv.scopes) > 0 && !slices.Contains(recv.scopes, scope):
+		return []string{fmt.Sprintf(
+			"The scope %q is not allowed, it must be one of: %s.",
+			scope, strings.Join(recv.scopes, ", "),
+		)}
+	}
+
+	return nil
+}
+
+func validateConventionalHeader(title string) []string {
+	m := conventionalHeaderPattern.FindStringSubmatch(title)
+	if m == nil {
+		return []string{
+			"The title must follow the Conventional Commits format: <type>(optional-scope): summary",
+		}
+	}
+
+	if !slices.Contains(conventionalTypes, m[1]) {
+		return []string{fmt.Sprintf(
+			"The type %q is not allowed, it must be one of: %s.",
+			m[1], strings.Join(conventionalTypes, ", "),
+		)}
+	}
+
+	if len(m[2]) > 0 && len(strings.TrimSpace(m[3])) == 0 {
+		return []string{"The scope must not be empty."}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package redact
+
+import (
+	"errors"
+	"io"
+	"regexp"
+)
+
+type (
+	redactConfig struct {
+		mode     Mode
+		custom   []detector
+		ignore   []string
+		reporter io.Writer
+	}
+
+	Opt func(*redactConfig) error
+)
+
+func WithMode(m Mode) Opt {
+	return func(rc *redactConfig) error {
+		if len(m) > 0 {
+			rc.mode = m
+		}
+
+		return nil
+	}
+}
+
+// WithPatterns of additional secrets. If a pattern has a
+// group, only the first group is treated as the secret.
+func WithPatterns(patterns ...string) Opt {
+	return func(rc *redactConfig) error {
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return errors.Join(ErrInvalidRegex, err)
+			}
+
+			d := detector{
+				name:    "custom",
+				pattern: re,
+			}
+			if re.NumSubexp() > 0 {
+				d.group = 1
+			}
+
+			rc.custom = append(rc.custom, d)
+		}
+
+		return nil
+	}
+}
+
+// WithIgnore skips the files matching any of the glob patterns.
+func WithIgnore(patterns ...string) Opt {
+	return func(rc *redactConfig) error {
+		rc.ignore = append(rc.ignore, patterns...)
+
+		return nil
+	}
+}
+
+// WithReporter of each secret that is found.
+func WithReporter(w io.Writer) Opt {
+	return func(rc *redactConfig) error {
+		rc.reporter = w
+
+		return nil
+	}
+}
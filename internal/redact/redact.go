@@ -0,0 +1,419 @@
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Mode of handling secrets found within patches.
+	Mode string
+
+	// Finding of a possible secret.
+	Finding struct {
+		File string
+		// Line of the file the secret was found on. For removed
+		// lines, this is the line of the original file.
+		Line     int
+		Detector string
+	}
+
+	// Redactor inspects patches for secrets before they
+	// are sent to the LLM.
+	Redactor struct {
+		config *redactConfig
+	}
+
+	detector struct {
+		name    string
+		pattern *regexp.Regexp
+		// group of the pattern holding the secret,
+		// zero for the entire match
+		group int
+		// minEntropy of the secret, zero for no minimum
+		minEntropy float64
+	}
+)
+
+const (
+	// ModeRedact replaces secrets with a placeholder.
+	ModeRedact = Mode("redact")
+	// ModeWarn reports secrets, but sends them unchanged.
+	ModeWarn = Mode("warn")
+	// ModeBlock refuses to send patches containing secrets.
+	ModeBlock = Mode("block")
+
+	// minHighEntropy, in bits per character, of a string
+	// that is considered a secret by the entropy detector.
+	minHighEntropy = 4.5
+	// minAssignmentEntropy of a value assigned to
+	// a secret-like name.
+	minAssignmentEntropy = 3.0
+
+	pemDetectorName     = "private-key"
+	entropyDetectorName = "high-entropy"
+)
+
+var (
+	ErrSecretFound  = errors.New("redact: secret found")
+	ErrInvalidMode  = errors.New("redact: unknown mode")
+	ErrInvalidRegex = errors.New("redact: invalid pattern")
+
+	builtinDetectors = []detector{
+		{name: "aws-access-key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{name: "github-token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+		{name: "gitlab-token", pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+		{name: "slack-token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+		{name: "stripe-key", pattern: regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{24,}\b`)},
+		{name: "google-api-key", pattern: regexp.MustCompile(`\bAIza[A-Za-z0-9_-]{35}\b`)},
+		{name: "api-key", pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}\b`)},
+		{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+		{
+			// a quoted string assigned to a secret-like name,
+			// e.g. password: "...", "api_key" = '...'
+			name: "secret-assignment",
+			pattern: regexp.MustCompile(
+				`(?i)(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)\w*["']?\s*[:=]\s*["']([^\s"']{8,})["']`,
+			),
+			group:      1,
+			minEntropy: minAssignmentEntropy,
+		},
+		{
+			// an env file style assignment, e.g. DB_PASSWORD=...
+			name: "env-secret",
+			pattern: regexp.MustCompile(
+				`^\s*(?:export\s+)?[A-Z0-9_]*(?:PASSWORD|PASSWD|SECRET|TOKEN|API_KEY|ACCESS_KEY|PRIVATE_KEY)[A-Z0-9_]*\s*=\s*["']?([^\s"']{8,})`,
+			),
+			group:      1,
+			minEntropy: minAssignmentEntropy,
+		},
+	}
+
+	entropyDetector = detector{
+		name:       entropyDetectorName,
+		pattern:    regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`),
+		minEntropy: minHighEntropy,
+	}
+
+	hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+	pemBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	pemEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+
+	// lockFiles are full of checksums, which are
+	// indistinguishable from high-entropy secrets.
+	lockFiles = []string{
+		"go.sum",
+		"package-lock.json",
+		"yarn.lock",
+		"pnpm-lock.yaml",
+		"Cargo.lock",
+		"poetry.lock",
+		"Gemfile.lock",
+		"composer.lock",
+	}
+)
+
+// Valid reports whether the mode is known.
+func (recv Mode) Valid() bool {
+	switch recv {
+	case ModeRedact,
+		ModeWarn,
+		ModeBlock:
+		return true
+	}
+
+	return false
+}
+
+func (recv Finding) String() string {
+	return fmt.Sprintf("%s:%d (%s)", recv.File, recv.Line, recv.Detector)
+}
+
+// New Redactor. Unless configured otherwise, secrets are redacted.
+func New(opts ...Opt) (*Redactor, error) {
+	config := &redactConfig{
+		mode: ModeRedact,
+	}
+
+	for _, o := range opts {
+		if err := o(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if !config.mode.Valid() {
+		return nil, ErrInvalidMode
+	}
+
+	return &Redactor{
+		config: config,
+	}, nil
+}
+
+// Seq wraps the sequence of patches, handling any secrets
+// they contain according to the mode.
+//
+// In block mode, the sequence ends with ErrSecretFound.
+func (recv *Redactor) Seq(seq iter.Seq2[string, error]) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for patch, err := range seq {
+			if err != nil {
+				yield(patch, err)
+
+				return
+			}
+
+			redacted, err := recv.Patch(patch)
+			if !yield(redacted, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Patch handles the secrets within the patch according to the mode.
+//
+// In block mode, ErrSecretFound is returned if any secrets are found.
+func (recv *Redactor) Patch(patch string) (string, error) {
+	redacted, findings := recv.Scan(patch)
+	if len(findings) == 0 {
+		return patch, nil
+	}
+
+	for _, f := range findings {
+		recv.report(f)
+	}
+
+	switch recv.config.mode {
+	case ModeWarn:
+		return patch, nil
+	case ModeBlock:
+		return "", fmt.Errorf("%w: %s", ErrSecretFound, findings[0])
+	}
+
+	return redacted, nil
+}
+
+// Scan the patch for secrets, returning it with all
+// secrets redacted and a finding for each secret.
+//
+// The patch may contain the changes of multiple files.
+func (recv *Redactor) Scan(patch string) (string, []Finding) {
+	var (
+		findings  []Finding
+		detectors []detector
+		lines     = strings.Split(patch, "\n")
+		file      string
+		inHunk    bool
+		inPEM     bool
+		// the current line of the original and new file
+		oldLine, newLine int
+	)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			file = patchFileName(line)
+			inHunk, inPEM = false, false
+
+			detectors = slices.Concat(builtinDetectors, recv.config.custom)
+			if !isLockFile(file) {
+				detectors = append(detectors, entropyDetector)
+			}
+
+			continue
+		}
+
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			inHunk = !recv.ignored(file)
+			oldLine, _ = strconv.Atoi(m[1])
+			newLine, _ = strconv.Atoi(m[2])
+
+			continue
+		}
+
+		if !inHunk || len(line) == 0 || line[0] == '\\' {
+			// outside of a hunk, or "\ No newline at end of file"
+			continue
+		}
+
+		// keep the diff's +, - or space prefix intact
+		prefix, content := line[:1], line[1:]
+
+		lineNo := newLine
+		switch prefix {
+		case "-":
+			lineNo = oldLine
+			oldLine++
+		case "+":
+			newLine++
+		default:
+			oldLine++
+			newLine++
+		}
+
+		switch {
+		case inPEM:
+			if pemEnd.MatchString(content) {
+				inPEM = false
+			} else {
+				lines[i] = prefix + placeholder(pemDetectorName)
+			}
+
+			continue
+		case pemBegin.MatchString(content):
+			inPEM = true
+			findings = append(findings, Finding{
+				File:     file,
+				Line:     lineNo,
+				Detector: pemDetectorName,
+			})
+
+			continue
+		}
+
+		for _, d := range detectors {
+			var found bool
+			content, found = d.redact(content)
+			if found {
+				findings = append(findings, Finding{
+					File:     file,
+					Line:     lineNo,
+					Detector: d.name,
+				})
+			}
+		}
+
+		lines[i] = prefix + content
+	}
+
+	return strings.Join(lines, "\n"), findings
+}
+
+func (recv *Redactor) report(f Finding) {
+	if recv.config.reporter == nil {
+		return
+	}
+
+	verb := "redacted"
+	switch recv.config.mode {
+	case ModeWarn:
+		verb = "found"
+	case ModeBlock:
+		verb = "blocked"
+	}
+
+	_, _ = fmt.Fprintf(recv.config.reporter,
+		"git-do: possible secret %s in %s\n", verb, f,
+	)
+}
+
+func (recv *Redactor) ignored(file string) bool {
+	for _, pattern := range recv.config.ignore {
+		if match(pattern, file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redact each secret matched by the detector.
+func (recv detector) redact(s string) (string, bool) {
+	var (
+		sb    strings.Builder
+		last  int
+		found bool
+	)
+
+	for _, loc := range recv.pattern.FindAllStringSubmatchIndex(s, -1) {
+		start, end := loc[2*recv.group], loc[2*recv.group+1]
+		if start < 0 || start == end {
+			// the group is optional and did not match, there's no secret
+			continue
+		}
+
+		if recv.minEntropy > 0 && entropy(s[start:end]) < recv.minEntropy {
+			continue
+		}
+
+		found = true
+		sb.WriteString(s[last:start])
+		sb.WriteString(placeholder(recv.name))
+		last = end
+	}
+
+	if !found {
+		return s, false
+	}
+
+	sb.WriteString(s[last:])
+
+	return sb.String(), true
+}
+
+func placeholder(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// entropy, in bits per character, of s.
+func entropy(s string) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var returner float64
+	n := float64(len([]rune(s)))
+	for _, c := range counts {
+		p := float64(c) / n
+		returner -= p * math.Log2(p)
+	}
+
+	return returner
+}
+
+func isLockFile(file string) bool {
+	return slices.Contains(lockFiles, path.Base(file))
+}
+
+// match the file against the glob pattern. Patterns without
+// a '/' match the file's base name, patterns ending in '/'
+// match everything within the directory.
+func match(pattern, file string) bool {
+	if dir, found := strings.CutSuffix(pattern, "/"); found {
+		return file == dir || strings.HasPrefix(file, dir+"/")
+	}
+
+	if !strings.Contains(pattern, "/") {
+		file = path.Base(file)
+	}
+
+	ok, _ := path.Match(pattern, file)
+
+	return ok
+}
+
+func patchFileName(patch string) string {
+	for line := range strings.SplitSeq(patch, "\n") {
+		if after, found := strings.CutPrefix(line, "diff --git "); found {
+			if i := strings.LastIndex(after, " b/"); i >= 0 {
+				return after[i+len(" b/"):]
+			}
+
+			return after
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,164 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// github issue, see https://docs.github.com/en/rest/issues/issues#get-an-issue
+func (recv *trackerConfig) github(ctx context.Context, id string) (*Issue, error) {
+	var issue struct {
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+	}
+
+	if err := recv.getJSON(
+		ctx,
+		fmt.Sprintf("%s/repos/%s/issues/%s",
+			recv.baseURL, recv.project, url.PathEscape(id)),
+		&issue,
+		func(req *http.Request) {
+			req.Header.Set("Accept", "application/vnd.github+json")
+			recv.bearer(req)
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	return &Issue{
+		ID:          id,
+		Title:       issue.Title,
+		Description: issue.Body,
+		URL:         issue.HTMLURL,
+	}, nil
+}
+
+// gitlab issue, see https://docs.gitlab.com/api/issues/#single-project-issue
+func (recv *trackerConfig) gitlab(ctx context.Context, id string) (*Issue, error) {
+	var issue struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		WebURL      string `json:"web_url"`
+	}
+
+	if err := recv.getJSON(
+		ctx,
+		fmt.Sprintf("%s/projects/%s/issues/%s",
+			recv.baseURL, url.PathEscape(recv.project), url.PathEscape(id)),
+		&issue,
+		func(req *http.Request) {
+			if len(recv.token) > 0 {
+				req.Header.Set("PRIVATE-TOKEN", recv.token)
+			}
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	return &Issue{
+		ID:          id,
+		Title:       issue.Title,
+		Description: issue.Description,
+		URL:         issue.WebURL,
+	}, nil
+}
+
+// jira issue, see https://developer.atlassian.com/cloud/jira/platform/rest/v2/api-group-issues/#api-rest-api-2-issue-issueidorkey-get
+func (recv *trackerConfig) jira(ctx context.Context, id string) (*Issue, error) {
+	var issue struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+		} `json:"fields"`
+	}
+
+	if err := recv.getJSON(
+		ctx,
+		fmt.Sprintf("%s/rest/api/2/issue/%s?fields=summary,description",
+			recv.baseURL, url.PathEscape(id)),
+		&issue,
+		func(req *http.Request) {
+			if len(recv.username) > 0 {
+				req.SetBasicAuth(recv.username, recv.token)
+			} else {
+				recv.bearer(req)
+			}
+		},
+	); err != nil {
+		return nil, err
+	}
+
+	if len(issue.Key) > 0 {
+		id = issue.Key
+	}
+
+	return &Issue{
+		ID:          id,
+		Title:       issue.Fields.Summary,
+		Description: issue.Fields.Description,
+		URL:         recv.baseURL + "/browse/" + id,
+	}, nil
+}
+
+// json issue from a generic endpoint. The base URL is a
+// template of the issue's URL, with access to the ID.
+func (recv *trackerConfig) json(ctx context.Context, id string) (*Issue, error) {
+	t, err := template.New("url").Parse(recv.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var u strings.Builder
+	if err := t.Execute(&u, struct {
+		ID string
+	}{
+		ID: url.PathEscape(id),
+	}); err != nil {
+		return nil, err
+	}
+
+	var issue map[string]any
+	if err := recv.getJSON(
+		ctx,
+		u.String(),
+		&issue,
+		recv.bearer,
+	); err != nil {
+		return nil, err
+	}
+
+	return &Issue{
+		ID:          id,
+		Title:       lookupField(issue, recv.titleField),
+		Description: lookupField(issue, recv.descriptionField),
+		URL:         u.String(),
+	}, nil
+}
+
+// lookupField of the object at the '.' separated path.
+func lookupField(obj map[string]any, path string) string {
+	var v any = obj
+	for key := range strings.SplitSeq(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+
+		v = m[key]
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,75 @@
+package tracker
+
+import (
+	"net/http"
+)
+
+type (
+	trackerConfig struct {
+		baseURL          string
+		project          string
+		username         string
+		token            string
+		titleField       string
+		descriptionField string
+		http             *http.Client
+	}
+
+	Opt func(*trackerConfig) error
+)
+
+// WithBaseURL of the tracker's API. For the JSON tracker this is
+// a template of the issue's URL, with access to the ID.
+func WithBaseURL(u string) Opt {
+	return func(tc *trackerConfig) error {
+		if len(u) > 0 {
+			tc.baseURL = u
+		}
+
+		return nil
+	}
+}
+
+// WithProject identifies the repository ("owner/repo") or
+// project path that issues belong to.
+func WithProject(p string) Opt {
+	return func(tc *trackerConfig) error {
+		tc.project = p
+
+		return nil
+	}
+}
+
+// WithCredentials used to authenticate with the tracker. If no
+// username is provided, the token is used as a bearer token.
+func WithCredentials(username, token string) Opt {
+	return func(tc *trackerConfig) error {
+		tc.username = username
+		tc.token = token
+
+		return nil
+	}
+}
+
+// WithFields of the JSON tracker's response holding the title
+// and description. Nested fields are separated by a '.'.
+func WithFields(title, description string) Opt {
+	return func(tc *trackerConfig) error {
+		if len(title) > 0 {
+			tc.titleField = title
+		}
+		if len(description) > 0 {
+			tc.descriptionField = description
+		}
+
+		return nil
+	}
+}
+
+func WithHTTPClient(c *http.Client) Opt {
+	return func(tc *trackerConfig) error {
+		tc.http = c
+
+		return nil
+	}
+}
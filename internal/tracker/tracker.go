@@ -0,0 +1,185 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type (
+	// Kind of issue tracker.
+	Kind string
+
+	// Issue as described by an issue tracker.
+	Issue struct {
+		ID          string
+		Title       string
+		Description string
+		URL         string
+	}
+
+	// Tracker retrieves the details of issues.
+	Tracker interface {
+		Issue(ctx context.Context, ref string) (*Issue, error)
+	}
+
+	adapter func(ctx context.Context, id string) (*Issue, error)
+)
+
+const (
+	KindGithub = Kind("github")
+	KindGitlab = Kind("gitlab")
+	KindJira   = Kind("jira")
+	KindJSON   = Kind("json")
+
+	defaultTimeout = 10 * time.Second
+)
+
+var (
+	ErrUnknownKind   = errors.New("tracker: unknown kind")
+	ErrMissingConfig = errors.New("tracker: missing required configuration")
+	ErrUnexpected    = errors.New("tracker: unexpected response")
+	ErrNotFound      = errors.New("tracker: issue not found")
+
+	defaultBaseURLs = map[Kind]string{
+		KindGithub: "https://api.github.com",
+		KindGitlab: "https://gitlab.com/api/v4",
+	}
+)
+
+// Valid reports whether the kind is known.
+func (recv Kind) Valid() bool {
+	switch recv {
+	case KindGithub,
+		KindGitlab,
+		KindJira,
+		KindJSON:
+		return true
+	}
+
+	return false
+}
+
+// DefaultBaseURL of the kind's API, if it has one.
+func (recv Kind) DefaultBaseURL() string {
+	return defaultBaseURLs[recv]
+}
+
+// New Tracker of the provided kind.
+func New(kind Kind, opts ...Opt) (Tracker, error) {
+	config := &trackerConfig{
+		baseURL:          kind.DefaultBaseURL(),
+		titleField:       "title",
+		descriptionField: "description",
+		http: &http.Client{
+			Timeout: defaultTimeout,
+		},
+	}
+
+	for _, o := range opts {
+		if err := o(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.baseURL) == 0 {
+		return nil, ErrMissingConfig
+	}
+
+	config.baseURL = strings.TrimSuffix(config.baseURL, "/")
+
+	var a adapter
+	switch kind {
+	case KindGithub:
+		a = config.github
+	case KindGitlab:
+		a = config.gitlab
+	case KindJira:
+		a = config.jira
+	case KindJSON:
+		a = config.json
+	default:
+		return nil, ErrUnknownKind
+	}
+
+	if kind == KindGithub || kind == KindGitlab {
+		if len(config.project) == 0 {
+			return nil, ErrMissingConfig
+		}
+	}
+
+	return a, nil
+}
+
+// Issue referenced by ref, which may be an ID or the URL of the issue.
+func (recv adapter) Issue(ctx context.Context, ref string) (*Issue, error) {
+	return recv(ctx, IssueID(ref))
+}
+
+// IssueID referenced by ref. URLs are reduced to their last
+// path segment and any leading '#' is removed.
+func IssueID(ref string) string {
+	ref = strings.TrimSpace(ref)
+
+	if u, err := url.Parse(ref); err == nil && len(u.Scheme) > 0 && len(u.Host) > 0 {
+		p := strings.TrimSuffix(u.Path, "/")
+		ref = p[strings.LastIndex(p, "/")+1:]
+	}
+
+	return strings.TrimPrefix(ref, "#")
+}
+
+// getJSON from the url into dst.
+func (recv *trackerConfig) getJSON(
+	ctx context.Context,
+	u string,
+	dst any,
+	authorize func(*http.Request),
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if authorize != nil {
+		authorize(req)
+	}
+
+	res, err := recv.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return fmt.Errorf("%w: %s", ErrUnexpected, res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, dst); err != nil {
+		return errors.Join(ErrUnexpected, err)
+	}
+
+	return nil
+}
+
+// bearer authorization, if a token was provided.
+func (recv *trackerConfig) bearer(req *http.Request) {
+	if len(recv.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+recv.token)
+	}
+}
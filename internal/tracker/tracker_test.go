@@ -0,0 +1,198 @@
+package tracker_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julianwyz/git-do/internal/tracker"
+)
+
+func TestIssueID(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"123":    "123",
+		"#123":   "123",
+		"PROJ-1": "PROJ-1",
+		"https://github.com/owner/repo/issues/42":  "42",
+		"https://jira.example.com/browse/PROJ-7/":  "PROJ-7",
+		"https://gitlab.com/group/proj/-/issues/9": "9",
+	} {
+		if id := tracker.IssueID(ref); id != expected {
+			t.Fatalf("expected %q from %q, got %q", expected, ref, id)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	var (
+		lastPath string
+		lastReq  *http.Request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.URL.Path
+		lastReq = r
+
+		var body any
+		switch r.URL.Path {
+		case "/repos/owner/repo/issues/42":
+			body = map[string]any{
+				"title":    "GitHub title",
+				"body":     "GitHub body",
+				"html_url": "https://github.com/owner/repo/issues/42",
+			}
+		case "/projects/group/proj/issues/9":
+			body = map[string]any{
+				"title":       "GitLab title",
+				"description": "GitLab body",
+				"web_url":     "https://gitlab.com/group/proj/-/issues/9",
+			}
+		case "/rest/api/2/issue/PROJ-7":
+			body = map[string]any{
+				"key": "PROJ-7",
+				"fields": map[string]any{
+					"summary":     "Jira title",
+					"description": "Jira body",
+				},
+			}
+		case "/tickets/55":
+			body = map[string]any{
+				"data": map[string]any{
+					"name":    "JSON title",
+					"details": "JSON body",
+				},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("github", func(t *testing.T) {
+		tr, err := tracker.New(
+			tracker.KindGithub,
+			tracker.WithBaseURL(server.URL),
+			tracker.WithProject("owner/repo"),
+			tracker.WithCredentials("", "gh-token"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		issue, err := tr.Issue(t.Context(), "https://github.com/owner/repo/issues/42")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if issue.Title != "GitHub title" || issue.Description != "GitHub body" {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+
+		if lastReq.Header.Get("Authorization") != "Bearer gh-token" {
+			t.Fatal("expected bearer token")
+		}
+	})
+
+	t.Run("gitlab", func(t *testing.T) {
+		tr, err := tracker.New(
+			tracker.KindGitlab,
+			tracker.WithBaseURL(server.URL),
+			tracker.WithProject("group/proj"),
+			tracker.WithCredentials("", "gl-token"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		issue, err := tr.Issue(t.Context(), "#9")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if issue.Title != "GitLab title" || issue.Description != "GitLab body" {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+
+		if lastReq.Header.Get("PRIVATE-TOKEN") != "gl-token" {
+			t.Fatal("expected private token")
+		}
+	})
+
+	t.Run("jira", func(t *testing.T) {
+		tr, err := tracker.New(
+			tracker.KindJira,
+			tracker.WithBaseURL(server.URL),
+			tracker.WithCredentials("jane@example.com", "jira-token"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		issue, err := tr.Issue(t.Context(), "PROJ-7")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if issue.Title != "Jira title" || issue.URL != server.URL+"/browse/PROJ-7" {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+
+		if user, pass, ok := lastReq.BasicAuth(); !ok ||
+			user != "jane@example.com" || pass != "jira-token" {
+			t.Fatal("expected basic auth")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		tr, err := tracker.New(
+			tracker.KindJSON,
+			tracker.WithBaseURL(server.URL+"/tickets/{{ .ID }}"),
+			tracker.WithFields("data.name", "data.details"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		issue, err := tr.Issue(t.Context(), "55")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if issue.Title != "JSON title" || issue.Description != "JSON body" {
+			t.Fatalf("unexpected issue: %+v", issue)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		tr, err := tracker.New(
+			tracker.KindJira,
+			tracker.WithBaseURL(server.URL),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tr.Issue(t.Context(), "NOPE-1"); !errors.Is(err, tracker.ErrNotFound) {
+			t.Fatalf("expected not found, got %v (%s)", err, lastPath)
+		}
+	})
+
+	t.Run("missing config", func(t *testing.T) {
+		if _, err := tracker.New(tracker.KindGithub); !errors.Is(err, tracker.ErrMissingConfig) {
+			t.Fatal("expected a project to be required")
+		}
+
+		if _, err := tracker.New(tracker.KindJira); !errors.Is(err, tracker.ErrMissingConfig) {
+			t.Fatal("expected a url to be required")
+		}
+
+		if _, err := tracker.New("unknown", tracker.WithBaseURL(server.URL)); !errors.Is(err, tracker.ErrUnknownKind) {
+			t.Fatal("expected unknown kind")
+		}
+	})
+}